@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// alias abbreviates the path prefix of modules or packages in printed paths, given as
+// prefix=name.
+type alias struct {
+	prefix string
+	name   string
+}
+
+// UnmarshalFlag implements flags.Unmarshaler.
+func (a *alias) UnmarshalFlag(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid alias %q, expected prefix=alias", value)
+	}
+	a.prefix, a.name = strings.TrimSuffix(value[:i], "/"), value[i+1:]
+	return nil
+}
+
+// abbreviator returns a function replacing the longest alias prefix of a path by its alias,
+// or with --abbrev the path of the main module by "…". Prefixes only match whole path
+// elements.
+func (res Result) abbreviator(opts printOptions) func(string) string {
+	aliases := opts.Aliases
+	if opts.Abbrev {
+		if mod := res.mainModule(); mod != "" {
+			aliases = append(aliases[:len(aliases):len(aliases)], alias{prefix: mod, name: "…"})
+		}
+	}
+	if len(aliases) == 0 {
+		return func(pkg string) string { return pkg }
+	}
+	return func(pkg string) string {
+		best := -1
+		for i, a := range aliases {
+			if (pkg == a.prefix || strings.HasPrefix(pkg, a.prefix+"/")) && (best < 0 || len(a.prefix) > len(aliases[best].prefix)) {
+				best = i
+			}
+		}
+		if best < 0 {
+			return pkg
+		}
+		return aliases[best].name + pkg[len(aliases[best].prefix):]
+	}
+}
+
+// mainModule returns the main module of the root, or the first main module of the graph,
+// or "" if there is none.
+func (res Result) mainModule() string {
+	if m := res.packages[res.Root].Module; m != nil && m.Main {
+		return m.Path
+	}
+	for _, p := range res.packages {
+		if p.Module != nil && p.Module.Main {
+			return p.Module.Path
+		}
+	}
+	return ""
+}
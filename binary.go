@@ -0,0 +1,92 @@
+package main
+
+import (
+	"debug/buildinfo"
+	"fmt"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// BinaryModule is a module embedded in the build info of a binary.
+type BinaryModule struct {
+	File    string `json:"file"`
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"`
+}
+
+type binaryCommand struct {
+	Args struct {
+		File   string `positional-arg-name:"binary"`
+		Module string `positional-arg-name:"module"`
+	} `positional-args:"yes" required:"yes"`
+	PathOpts `group:"Path Options"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+func (c *binaryCommand) Execute(args []string) (err error) {
+	bi, err := buildinfo.ReadFile(c.Args.File)
+	if err != nil {
+		return err
+	}
+	var found *BinaryModule
+	for _, dep := range bi.Deps {
+		if dep.Path != c.Args.Module {
+			continue
+		}
+		found = &BinaryModule{File: c.Args.File, Module: dep.Path, Version: dep.Version}
+		if dep.Replace != nil {
+			found.Replace = dep.Replace.Path + " " + dep.Replace.Version
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("module %s is not in %s", c.Args.Module, c.Args.File)
+	}
+
+	// Analyze the source graph the way the binary was built.
+	opts := *c.opts
+	opts.PathOpts = c.PathOpts
+	opts.Module = true
+	for _, s := range bi.Settings {
+		switch {
+		case s.Key == "GOOS" && opts.GOOS == "":
+			opts.GOOS = s.Value
+		case s.Key == "GOARCH" && opts.GOARCH == "":
+			opts.GOARCH = s.Value
+		case s.Key == "-tags" && opts.Tags == "":
+			opts.Tags = s.Value
+		}
+	}
+	if opts.defaultPattern() && bi.Path != "command-line-arguments" {
+		if mainModules, err := mainModulePaths(); err == nil && contains(mainModules, bi.Main.Path) {
+			opts.Pattern = []string{bi.Path}
+		}
+	}
+	results, err := explain(opts, []string{c.Args.Module})
+	if err != nil {
+		return err
+	}
+	for i := range results {
+		results[i].Binary = found
+		if v := results[i].versionOf(results[i].Target); v != "" && v != found.Version {
+			warnf("the binary has %s@%s but the source graph %s", found.Module, found.Version, v)
+		}
+	}
+
+	out, err := openOutput(opts)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	popts := newPrintOptions(c.parser, opts, out)
+	if popts.Format == "text" {
+		fmt.Fprintf(out, "%s contains %s@%s", found.File, found.Module, found.Version)
+		if found.Replace != "" {
+			fmt.Fprintf(out, " => %s", found.Replace)
+		}
+		fmt.Fprint(out, "\n\n")
+	}
+	return printResults(out, popts, c.Args.Module, results)
+}
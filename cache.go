@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cacheKey hashes what the go list output depends on: the go version, the go.mod and go.sum
+// of the main module or the go.work of the workspace, and the query. Source files are not
+// hashed, so the cache goes stale when imports change without changing go.mod.
+func cacheKey(patterns []string, includeTest bool, listFlags, env []string) (string, error) {
+	output, err := goCommand("env", "GOVERSION", "GOMOD", "GOWORK", "GOFLAGS").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	h := sha256.New()
+	fmt.Fprintf(h, "%q %v %q %q %q %q\n", lines, includeTest, patterns, listFlags, env, goDir)
+	var files []string
+	if goModFile != "" {
+		files = append(files, goModFile, strings.TrimSuffix(goModFile, ".mod")+".sum")
+	} else if len(lines) > 1 && lines[1] != "" && lines[1] != os.DevNull {
+		files = append(files, lines[1], strings.TrimSuffix(lines[1], ".mod")+".sum")
+	}
+	if len(lines) > 2 && lines[2] != "" && lines[2] != "off" {
+		files = append(files, lines[2], lines[2]+".sum")
+	}
+	for _, name := range files {
+		fmt.Fprintf(h, "%s\n", name)
+		f, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cachedGoList is runGoList backed by an on-disk cache under the user cache directory.
+// Failing to read or write the cache only costs running go list again.
+func cachedGoList(opts Opts, patterns []string, includeTest bool, listFlags, env []string) ([]Package, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return runGoList(patterns, includeTest, listFlags, env)
+	}
+	key, err := cacheKey(patterns, includeTest, listFlags, env)
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(dir, "gomodwhy", key+".json")
+	if data, err := os.ReadFile(file); err == nil {
+		var packages []Package
+		if err := json.Unmarshal(data, &packages); err == nil {
+			debugf("Reusing cached go list output %s", file)
+			return packages, nil
+		}
+	}
+
+	packages, err := runGoList(patterns, includeTest, listFlags, env)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCache(file, packages); err != nil {
+		debugf("Cannot write cache: %v", err)
+	}
+	return packages, nil
+}
+
+// writeCache writes the packages to file through a temporary file, so that concurrent
+// invocations never read a partial cache entry.
+func writeCache(file string, packages []Package) error {
+	data, err := json.Marshal(packages)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// CgoPackage is a package using cgo with the shortest import chain from the root to it.
+type CgoPackage struct {
+	Package  string   `json:"package"`
+	CgoFiles []string `json:"cgo_files"`
+	Path     []string `json:"path"`
+}
+
+type cgoCommand struct {
+	Std bool `long:"std" description:"also report standard library packages, which mostly have pure Go fallbacks"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// cgoPackages returns the packages reachable from the roots which use cgo.
+func cgoPackages(g *graph, std bool) []CgoPackage {
+	res := make([]CgoPackage, 0)
+	for pkg, chain := range shortestChains(g.roots, g.forward) {
+		p := g.pkgMap[pkg]
+		if len(p.CgoFiles) == 0 || (p.Standard && !std) {
+			continue
+		}
+		res = append(res, CgoPackage{Package: pkg, CgoFiles: p.CgoFiles, Path: chain})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Package < res[j].Package })
+	return res
+}
+
+func (c *cgoCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	// cgo files are only listed when cgo is enabled.
+	opts := *c.opts
+	opts.forceCgo = true
+	g, err := loadGraph(opts)
+	if err != nil {
+		return err
+	}
+	pkgs := cgoPackages(g, c.Std)
+
+	return writeReport(c.parser, opts, "cgo", Result{Root: g.root, Cgo: pkgs}, func(w io.Writer) error {
+		return printCgo(w, pkgs)
+	})
+}
+
+func printCgo(w io.Writer, pkgs []CgoPackage) error {
+	fmt.Fprintln(w, "# cgo")
+	if len(pkgs) == 0 {
+		fmt.Fprintln(w, "no package using cgo found, the build works with CGO_ENABLED=0")
+		return nil
+	}
+	for _, p := range pkgs {
+		fmt.Fprintf(w, "%s (%d cgo file(s))\n", p.Package, len(p.CgoFiles))
+		for _, pkg := range p.Path {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
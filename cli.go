@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// newParser returns the parser of the command line into opts, with every command. The
+// commands share the options loading the graph, which are the global options, and run
+// through the command handler preparing the go command, profiling and the timeout.
+func newParser(opts *Opts) *flags.Parser {
+	// main prints the errors, the ones of failed runs being already printed.
+	parser := flags.NewParser(opts, flags.HelpFlag|flags.PassDoubleDash)
+	parser.Name = "gomodwhy"
+	parser.Usage = "[options] <target-pkg>..."
+	parser.SubcommandsOptional = true
+	parser.AddCommand("path", "List the import chains to the targets",
+		"List every import chain from the root to the targets, the default command when targets are given without a command.",
+		&pathCommand{parser: parser, opts: opts})
+	parser.AddCommand("graph", "Print the subgraph of the import chains to the targets",
+		"Print the union of the import edges on any chain from the root to the targets, same as path --subgraph.",
+		&pathCommand{parser: parser, opts: opts, subgraph: true})
+	parser.AddCommand("diff", "Compare the import chains to the targets with a saved graph",
+		"List the import chains to the targets added and removed since the graph saved by --save-graph, e.g. on the main branch, to review what a dependency change brings in.",
+		&diffCommand{parser: parser, opts: opts})
+	parser.AddCommand("cycles", "Detect import cycles",
+		"Detect and print import cycles in the loaded graph. Cycles only exist through test imports, so this is mostly useful with --include-test.",
+		&cyclesCommand{parser: parser, opts: opts})
+	parser.AddCommand("unused", "List go.mod requirements without any import path",
+		"List the modules required by go.mod that no package of the main module imports, directly or transitively. They are candidates for go mod tidy, or only needed by tools.",
+		&unusedCommand{parser: parser, opts: opts})
+	parser.AddCommand("version", "Explain the selected version of a module",
+		"Explain which requirers in the module graph force the version of the module selected by minimal version selection.",
+		&versionCommand{parser: parser, opts: opts})
+	parser.AddCommand("sum", "Explain a go.sum entry",
+		"Map a go.sum entry, given as module@version or as the go.sum line, back to the requirement chain causing it, and tell whether packages of it are built or only its go.mod is needed.",
+		&sumCommand{parser: parser, opts: opts})
+	parser.AddCommand("majors", "Detect modules built with multiple major versions",
+		"Report the modules whose several major versions are built, e.g. both foo and foo/v2, with the shortest import chain pulling in each of them.",
+		&majorsCommand{parser: parser, opts: opts})
+	parser.AddCommand("search", "Search packages and modules of the graph",
+		"List the packages and modules of the loaded graph whose path contains the keyword, ignoring case, to find the exact target before asking why it is imported.",
+		&searchCommand{parser: parser, opts: opts})
+	parser.AddCommand("report", "Report why every third-party module is built",
+		"Print the shortest import chain from the root to every third-party module of the build.",
+		&reportCommand{parser: parser, opts: opts})
+	parser.AddCommand("binary", "Explain why a module is in a compiled binary",
+		"Read the build info embedded in a Go binary and explain, with the source graph loaded for the same platform and build tags, why the module is in the binary.",
+		&binaryCommand{parser: parser, opts: opts})
+	parser.AddCommand("cgo", "Explain why packages using cgo are built",
+		"List the packages of the graph using cgo with the shortest import chain pulling in each of them, to find what prevents a CGO_ENABLED=0 build.",
+		&cgoCommand{parser: parser, opts: opts})
+	parser.AddCommand("goversion", "Explain which dependencies force the go version",
+		"Compare the go directives of the modules of the build list with the one of the main module and show the requirement chain of each module needing a newer go version.",
+		&goVersionCommand{parser: parser, opts: opts})
+	parser.AddCommand("platforms", "List dependencies only built on some platforms",
+		"Load the graph on every platform of --platforms and list the third-party packages missing on at least one of them, with the platforms they are built on and their shortest import chain.",
+		&platformsCommand{parser: parser, opts: opts})
+	parser.AddCommand("serve", "Answer queries of --server clients from a graph loaded once",
+		"Load the packages once and answer the queries of gomodwhy --server clients over a unix socket, so that repeated queries with different targets, depths or filters do not run go list again.",
+		&serveCommand{parser: parser, opts: opts})
+	parser.AddCommand("repl", "Query a graph loaded once interactively",
+		"Load the packages once, then read queries, targets with the options of a single query, and commands keeping options such as the depth or filters across queries, printing the answer of each query.",
+		&replCommand{parser: parser, opts: opts})
+	parser.AddCommand("scan", "Explain a target in every module of a directory tree",
+		"Discover every go.mod below a directory, explain the targets from all packages of each module and aggregate the modules importing them with their shortest import chain.",
+		&scanCommand{parser: parser, opts: opts})
+	parser.AddCommand("completion", "Print a shell completion script",
+		"Print the script completing the options, commands and target packages of gomodwhy in bash, zsh or fish. Target packages are listed with go list, whose output is cached until go.mod changes.",
+		&completionCommand{parser: parser, opts: opts})
+	if cmd, err := parser.AddCommand("__complete", "Print completion candidates", "", &completeCommand{parser: parser}); err == nil {
+		cmd.Hidden = true
+	}
+
+	parser.CommandHandler = func(cmd flags.Commander, args []string) error {
+		if cmd == nil {
+			return nil
+		}
+		setLogLevel(*opts)
+		if err := opts.prepare(); err != nil {
+			return err
+		}
+		if err := startProfiling(*opts); err != nil {
+			return err
+		}
+		defer stopProfiling()
+		defer startTimeout(*opts)()
+		return cmd.Execute(args)
+	}
+
+	return parser
+}
+
+// commandArgs splits the command line arguments around the name of the command they run,
+// returning the command with the arguments up to its name and the ones after it. Targets,
+// or path options, given without a command run the path command, whose name is added then.
+// A target named like a command is given after it or after --, e.g. gomodwhy -- path.
+func commandArgs(parser *flags.Parser, args []string) (cmd *flags.Command, head, tail []string) {
+	path := parser.Find("path")
+	i := 0
+	for ; i < len(args) && args[i] != "--"; i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			if cmd := parser.Find(arg); cmd != nil {
+				return cmd, args[:i+1], args[i+1:]
+			}
+			break
+		}
+		opts, value := argOptions(path, arg)
+		for _, opt := range opts {
+			if parser.FindOptionByLongName(opt.LongName) == nil {
+				return path, []string{"path"}, args
+			}
+		}
+		if value {
+			i++
+		}
+	}
+	if i >= len(args) {
+		return parser.Command, nil, args
+	}
+	return path, []string{"path"}, args
+}
+
+// pathCommand explains why the targets are imported, printing the import chains or, with
+// subgraph, their union.
+type pathCommand struct {
+	PathOpts `group:"Path Options"`
+
+	parser   *flags.Parser
+	opts     *Opts
+	subgraph bool
+}
+
+func (c *pathCommand) Execute(args []string) (err error) {
+	opts := *c.opts
+	opts.PathOpts = c.PathOpts
+	if c.subgraph {
+		opts.Subgraph = true
+	}
+	if opts.Compat {
+		if c.subgraph || opts.Stream || opts.PageSize > 0 || opts.Offset > 0 || len(opts.Platforms) > 0 {
+			return errors.New("--compat cannot be used with graph, --stream or --platforms")
+		}
+		opts = compatOpts(c.parser, opts)
+	}
+	// The timeout bounds the whole process, it would cut every run after the first one short.
+	if opts.Watch && (opts.Server != "" || opts.LoadGraph != "" || opts.Stream || opts.PageSize > 0 || opts.Offset > 0 || opts.Timeout > 0) {
+		return errors.New("--watch cannot be used with --server, --load-graph, --stream or --timeout")
+	}
+	targetArgs := args
+	if opts.TargetsFile != "" {
+		fileTargets, err := readTargets(opts.TargetsFile)
+		if err != nil {
+			return err
+		}
+		targetArgs = append(targetArgs, fileTargets...)
+	}
+	if len(targetArgs) == 0 {
+		c.parser.WriteHelp(os.Stderr)
+		exit(1)
+	}
+
+	if opts.Server != "" {
+		return c.askServer(opts, targetArgs)
+	}
+	if opts.Compat {
+		return c.compat(opts, targetArgs)
+	}
+	if opts.Watch {
+		return c.watch(opts, targetArgs)
+	}
+	if opts.PageSize > 0 || opts.Offset > 0 {
+		opts.Stream = true
+	}
+	var out *os.File
+	if opts.Stream {
+		if err := opts.checkStream(outputFormat(c.parser, opts)); err != nil {
+			return err
+		}
+		if out, err = openOutput(opts); err != nil {
+			return err
+		}
+		defer closeOutput(out, &err)
+		opts.stream = &pathStream{w: out, opts: newPrintOptions(c.parser, opts, out), pageSize: opts.PageSize, offset: opts.Offset}
+		if opts.PageSize > 0 && isTerminal(os.Stdin) && isTerminal(out) {
+			opts.stream.prompt = bufio.NewReader(os.Stdin)
+		}
+	}
+
+	handleInterrupt()
+	stopProgress := func() {}
+	if opts.Progress {
+		stopProgress = startProgress()
+	}
+	var results []Result
+	if len(opts.Platforms) > 0 {
+		results, err = explainPlatforms(opts, targetArgs)
+	} else {
+		results, err = explain(opts, targetArgs)
+	}
+	stopProgress()
+	if err == errNoPackage {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		c.parser.WriteHelp(os.Stderr)
+		exit(1)
+	}
+	if err != nil {
+		return err
+	}
+	failed := opts.checkFound(results)
+	if opts.stream == nil {
+		if out, err = openOutput(opts); err != nil {
+			return err
+		}
+		defer closeOutput(out, &err)
+		popts := newPrintOptions(c.parser, opts, out)
+		if err := printResults(out, popts, strings.Join(targetArgs, ", "), results); err != nil {
+			return err
+		}
+	}
+	if failed != nil {
+		fmt.Fprintln(os.Stderr, failed.Error())
+		return errFound
+	}
+	return nil
+}
+
+// askServer prints the answer of the --server server for the targets.
+func (c *pathCommand) askServer(opts Opts, targetArgs []string) (err error) {
+	out, err := openOutput(opts)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	// The format is resolved here, where the command line is told apart from the config file.
+	q := query{Args: append(opts.args[:len(opts.args):len(opts.args)], "--format="+outputFormat(c.parser, opts)), Targets: targetArgs, Color: opts.useColor(out)}
+	if c.subgraph {
+		q.Args = append(q.Args, "--subgraph")
+	}
+	a, err := ask(opts.Server, q)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(out, a.Output)
+	if a.Failed != "" {
+		fmt.Fprintln(os.Stderr, a.Failed)
+		return errFound
+	}
+	return nil
+}
+
+// compat prints the output of go mod why for the targets.
+func (c *pathCommand) compat(opts Opts, targetArgs []string) error {
+	g, err := loadGraph(opts)
+	if err == errNoPackage {
+		fmt.Fprintf(os.Stderr, "%v\n\n", err)
+		c.parser.WriteHelp(os.Stderr)
+		exit(1)
+	}
+	if err != nil {
+		return err
+	}
+	return writeOutput(opts, func(w io.Writer) error {
+		_, err := fmt.Fprint(w, compatWhy(opts, g, targetArgs))
+		return err
+	})
+}
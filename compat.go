@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// compatOpts returns the options of a --compat query, loading the graph go mod why
+// explains: the packages of the main module and their tests, unless --pattern is given.
+func compatOpts(parser *flags.Parser, opts Opts) Opts {
+	if !explicitOption(parser, "pattern") {
+		opts.Pattern = []string{"./..."}
+	}
+	opts.IncludeTest = true
+	return opts
+}
+
+// compatWhy returns the output of go mod why for the target arguments, or of go mod why -m
+// with --module: for every argument, a "# target" header followed by the shortest import
+// chain from a package of the main module to the target, or to the closest package of the
+// target module, or a note that the main module does not need it. Like go mod why, a test
+// import goes through the importing package's ".test" line.
+func compatWhy(opts Opts, g *graph, targetArgs []string) string {
+	depth, parent := compatSearch(compatSources(opts, g), g.forward)
+	testOnly := testOnlyEdges(g.packages)
+	var sb strings.Builder
+	for i, arg := range targetArgs {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "# %s\n", arg)
+		target, kind := arg, "package"
+		if opts.Module {
+			target, kind = "", "module"
+			for _, pkg := range modulePackages(arg, g.packages) {
+				if d, ok := depth[pkg]; ok && (target == "" || d < depth[target]) {
+					target = pkg
+				}
+			}
+		}
+		if _, ok := depth[target]; !ok {
+			fmt.Fprintf(&sb, "(main module does not need %s %s)\n", kind, arg)
+			continue
+		}
+		var chain []string
+		for pkg := target; pkg != ""; pkg = parent[pkg] {
+			chain = append(chain, pkg)
+		}
+		for j := len(chain) - 1; j >= 0; j-- {
+			fmt.Fprintln(&sb, chain[j])
+			if j > 0 && testOnly[edge{from: chain[j], to: chain[j-1]}] {
+				fmt.Fprintf(&sb, "%s.test\n", chain[j])
+			}
+		}
+	}
+	return sb.String()
+}
+
+// compatSources returns the packages go mod why starts from: the --from package, or else the
+// packages of the main module, or of the module of a module@version pattern, sorted.
+func compatSources(opts Opts, g *graph) []string {
+	if opts.From != "" {
+		return g.roots
+	}
+	var sources []string
+	for _, p := range g.packages {
+		if p.Module == nil {
+			continue
+		}
+		if (opts.fromModule == "" && p.Module.Main) || p.Module.Path == opts.fromModule {
+			sources = append(sources, p.ImportPath)
+		}
+	}
+	if len(sources) == 0 {
+		return g.roots
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// compatSearch runs a breadth-first search from all sources, returning the distance of
+// every reached package and its parent on the first shortest chain found, "" for sources.
+func compatSearch(sources []string, forward map[string][]string) (map[string]int, map[string]string) {
+	depth := make(map[string]int)
+	parent := make(map[string]string)
+	queue := make([]string, 0, len(sources))
+	for _, s := range sources {
+		if _, ok := depth[s]; !ok {
+			depth[s] = 0
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range forward[node] {
+			if _, ok := depth[next]; !ok {
+				depth[next] = depth[node] + 1
+				parent[next] = node
+				queue = append(queue, next)
+			}
+		}
+	}
+	return depth, parent
+}
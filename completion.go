@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type completionCommand struct {
+	Args struct {
+		Shell string `positional-arg-name:"bash|zsh|fish"`
+	} `positional-args:"yes" required:"yes"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// completionScripts are the shell scripts completing the command line with the candidates
+// printed by the hidden __complete command.
+var completionScripts = map[string]string{
+	"bash": `_gomodwhy() {
+	local IFS=$'\n'
+	COMPREPLY=($(gomodwhy __complete -- "${COMP_WORDS[@]:1:$COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _gomodwhy gomodwhy
+`,
+	"zsh": `#compdef gomodwhy
+_gomodwhy() {
+	local -a candidates
+	candidates=("${(@f)$(gomodwhy __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	candidates=(${candidates:#})
+	if (( ${#candidates} )); then
+		compadd -Q -- $candidates
+	else
+		_files
+	fi
+}
+compdef _gomodwhy gomodwhy
+`,
+	"fish": `function __gomodwhy_complete
+	set -l words (commandline -opc) (commandline -ct)
+	gomodwhy __complete -- $words[2..-1] 2>/dev/null
+end
+complete -c gomodwhy -f -a '(__gomodwhy_complete)'
+`,
+}
+
+func (c *completionCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	script, ok := completionScripts[c.Args.Shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %s, expected bash, zsh or fish", c.Args.Shell)
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// completeCommand prints the candidates completing the last of the words given after --,
+// the command line without the program name. It backs the completion scripts and prints
+// nothing rather than failing.
+type completeCommand struct {
+	parser *flags.Parser
+}
+
+func (c *completeCommand) Execute(words []string) error {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, prev := words[len(words)-1], ""
+	if len(words) > 1 {
+		prev = words[len(words)-2]
+		if prev == "=" && len(words) > 2 { // bash splits --format=json at the '='
+			prev = words[len(words)-3]
+		}
+	}
+	if i := strings.Index(cur, "="); strings.HasPrefix(cur, "--") && i > 0 {
+		prev, cur = cur[:i], cur[i+1:]
+	}
+	// Without a command, the options of the path command apply.
+	cmd, _, _ := commandArgs(c.parser, words[:len(words)-1])
+	if cmd == c.parser.Command {
+		cmd = c.parser.Find("path")
+	}
+	option := func(word string) *flags.Option {
+		return wordOption(cmd, word)
+	}
+	var candidates []string
+	if opt := option(prev); opt != nil && takesValue(opt) {
+		candidates = opt.Choices
+	} else if strings.HasPrefix(cur, "-") {
+		for _, opt := range commandOptions(c.parser, cmd) {
+			if opt.LongName != "" && !opt.Hidden {
+				candidates = append(candidates, "--"+opt.LongName)
+			}
+		}
+	} else {
+		if !hasPositional(words[:len(words)-1], option) {
+			for _, cmd := range c.parser.Commands() {
+				if !cmd.Hidden {
+					candidates = append(candidates, cmd.Name)
+				}
+			}
+		}
+		candidates = append(candidates, completionPackages(words[:len(words)-1])...)
+	}
+	for _, s := range candidates {
+		if strings.HasPrefix(s, cur) {
+			fmt.Println(s)
+		}
+	}
+	return nil
+}
+
+// wordOption returns the option of cmd, or of the main command, named by the word, or nil.
+func wordOption(cmd *flags.Command, word string) *flags.Option {
+	switch {
+	case strings.HasPrefix(word, "--"):
+		return cmd.FindOptionByLongName(word[2:])
+	case strings.HasPrefix(word, "-") && len(word) == 2:
+		return cmd.FindOptionByShortName(rune(word[1]))
+	}
+	return nil
+}
+
+// takesValue reports whether the option is followed by a value.
+func takesValue(opt *flags.Option) bool {
+	return opt.Field().Type.Kind() != reflect.Bool && !opt.OptionalArgument
+}
+
+// hasPositional reports whether a positional argument, a target or a command, is among
+// the words.
+func hasPositional(words []string, option func(string) *flags.Option) bool {
+	for i := 0; i < len(words); i++ {
+		switch w := words[i]; {
+		case strings.HasPrefix(w, "-"):
+			if opt := option(w); opt != nil && !strings.Contains(w, "=") && takesValue(opt) {
+				i++
+			}
+		case w != "=":
+			return true
+		}
+	}
+	return false
+}
+
+// completionPackages returns the packages of the graph loaded with the pattern, directory
+// and module options among the words. The go list output is cached like with --cache, so
+// that only the first completion after a go.mod change runs go list.
+func completionPackages(words []string) []string {
+	var opts Opts
+	flags.NewParser(&opts, flags.IgnoreUnknown).ParseArgs(words)
+	goDir, goModFile = opts.Chdir, opts.Modfile
+	if strings.Contains(strings.Join(opts.Pattern, " "), "@") {
+		return nil
+	}
+	opts.Cache = true
+	packages, _, err := listPackages(opts, opts.patterns())
+	if err != nil {
+		return nil
+	}
+	paths := make([]string, 0, len(packages))
+	for _, p := range packages {
+		paths = append(paths, p.ImportPath)
+	}
+	sort.Strings(paths)
+	return paths
+}
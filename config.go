@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// configNames are the names of the repo-local config file, looked up from the current
+// directory up to the root of the repository.
+var configNames = []string{".gomodwhy.yaml", ".gomodwhy.yml"}
+
+// findConfig returns the config file of the current directory or of its closest parent,
+// stopping at the directory holding .git, or "" if there is none.
+func findConfig() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		for _, name := range configNames {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return filepath.Join(dir, name), nil
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// configFlag returns the value of the last --config among the command line arguments, and
+// whether it is given.
+func configFlag(args []string) (string, bool) {
+	name, ok := "", false
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--config" && i+1 < len(args) {
+			name, ok = args[i+1], true
+		}
+		if strings.HasPrefix(arg, "--config=") {
+			name, ok = strings.TrimPrefix(arg, "--config="), true
+		}
+	}
+	return name, ok
+}
+
+// configArgs returns the options of the config file given by --config, or else found by
+// findConfig, as command line arguments to put before the actual ones, after the name of
+// the command. The options given among the actual ones are left out, so that they replace
+// the values of the file rather than add to them, and so are the options of other commands,
+// so that one file serves every command. An empty --config disables the config file.
+func configArgs(parser *flags.Parser, cmd *flags.Command, args []string) ([]string, error) {
+	name, ok := configFlag(args)
+	if !ok {
+		var err error
+		if name, err = findConfig(); err != nil {
+			return nil, err
+		}
+	}
+	if name == "" {
+		return nil, nil
+	}
+	values, err := readConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	given := givenOptions(cmd, args)
+	var res []string
+	for _, v := range values {
+		opt := cmd.FindOptionByLongName(v.key)
+		if opt == nil && v.key != "config" && anyOption(parser, v.key) {
+			continue
+		}
+		if opt == nil || v.key == "config" {
+			return nil, fmt.Errorf("%s:%d: unknown option %s", name, v.line, v.key)
+		}
+		if given[opt] {
+			continue
+		}
+		arg, err := optionArg(opt, v.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, v.line, err)
+		}
+		if arg != "" {
+			res = append(res, arg)
+		}
+	}
+	return res, nil
+}
+
+// givenOptions returns the options of cmd, or of its parents, given among the command line
+// arguments.
+func givenOptions(cmd *flags.Command, args []string) map[*flags.Option]bool {
+	given := make(map[*flags.Option]bool)
+	for i := 0; i < len(args) && args[i] != "--"; i++ {
+		opts, value := argOptions(cmd, args[i])
+		for _, opt := range opts {
+			given[opt] = true
+		}
+		if value {
+			i++
+		}
+	}
+	return given
+}
+
+// argOptions returns the options of cmd, or of its parents, named by a command line
+// argument, several for grouped short options, and whether the next argument is the value
+// of the last one.
+func argOptions(cmd *flags.Command, arg string) ([]*flags.Option, bool) {
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		name, _, hasValue := strings.Cut(arg[2:], "=")
+		opt := cmd.FindOptionByLongName(name)
+		if opt == nil {
+			return nil, false
+		}
+		return []*flags.Option{opt}, !hasValue && takesValue(opt)
+	case strings.HasPrefix(arg, "-") && len(arg) > 1:
+		// Short options can be grouped, the first one taking a value ending the group.
+		var opts []*flags.Option
+		for j := 1; j < len(arg); j++ {
+			opt := cmd.FindOptionByShortName(rune(arg[j]))
+			if opt == nil {
+				break
+			}
+			opts = append(opts, opt)
+			if takesValue(opt) {
+				return opts, j == len(arg)-1
+			}
+		}
+		return opts, false
+	}
+	return nil, false
+}
+
+// optionArg returns the command line argument setting the option to the value, or "" for
+// a false boolean.
+func optionArg(opt *flags.Option, value string) (string, error) {
+	if opt.Field().Type.Kind() != reflect.Bool {
+		return "--" + opt.LongName + "=" + value, nil
+	}
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return "--" + opt.LongName, nil
+	case "false", "no", "off", "0", "":
+		return "", nil
+	default:
+		return "", fmt.Errorf("invalid boolean %q for %s", value, opt.LongName)
+	}
+}
+
+// configValue is a value of an option in the config file, lists giving one per item.
+type configValue struct {
+	key   string
+	value string
+	line  int
+}
+
+// readConfig reads the options of a config file. It supports the subset of YAML needed for
+// options: a mapping of option long names to scalars, or to lists of scalars written as
+// block sequences or in flow style, with comments and quoted strings.
+func readConfig(name string) ([]configValue, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var values []configValue
+	list := "" // key of the block sequence being read
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := stripComment(scanner.Text())
+		if strings.TrimSpace(line) == "" || line == "---" {
+			continue
+		}
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if list == "" || trimmed == "-" {
+				return nil, fmt.Errorf("%s:%d: unexpected list item", name, n)
+			}
+			item, err := unquote(strings.TrimSpace(trimmed[1:]))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", name, n, err)
+			}
+			values = append(values, configValue{key: list, value: item, line: n})
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, fmt.Errorf("%s:%d: nested mappings are not supported", name, n)
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: expected option: value", name, n)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		list = ""
+		switch {
+		case value == "":
+			list = key
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			for _, item := range strings.Split(value[1:len(value)-1], ",") {
+				if item = strings.TrimSpace(item); item == "" {
+					continue
+				}
+				if item, err = unquote(item); err != nil {
+					return nil, fmt.Errorf("%s:%d: %v", name, n, err)
+				}
+				values = append(values, configValue{key: key, value: item, line: n})
+			}
+		default:
+			if value, err = unquote(value); err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", name, n, err)
+			}
+			values = append(values, configValue{key: key, value: value, line: n})
+		}
+	}
+	return values, scanner.Err()
+}
+
+// stripComment removes a trailing # comment outside of quotes, and trailing spaces.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+// unquote returns a scalar without its single or double quotes.
+func unquote(s string) (string, error) {
+	if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+		if len(s) < 2 || s[len(s)-1] != s[0] {
+			return "", errors.New("unterminated quoted string")
+		}
+		return s[1 : len(s)-1], nil
+	}
+	return s, nil
+}
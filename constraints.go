@@ -0,0 +1,143 @@
+package main
+
+import (
+	"go/build/constraint"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// knownOS and knownArch are the GOOS and GOARCH values recognized in file name suffixes
+// like _linux.go or _windows_amd64.go, as listed in go/build/syslist.go.
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+		"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+		"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+		"windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+		"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+		"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+		"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true,
+		"sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// nameConstraint returns the constraint implied by the GOOS and GOARCH suffixes of a file
+// name, or nil.
+func nameConstraint(name string) constraint.Expr {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".go"), "_test")
+	parts := strings.Split(name, "_")
+	if len(parts) < 2 {
+		return nil
+	}
+	last := parts[len(parts)-1]
+	if len(parts) >= 3 && knownOS[parts[len(parts)-2]] && knownArch[last] {
+		return &constraint.AndExpr{X: &constraint.TagExpr{Tag: parts[len(parts)-2]}, Y: &constraint.TagExpr{Tag: last}}
+	}
+	if knownOS[last] || knownArch[last] {
+		return &constraint.TagExpr{Tag: last}
+	}
+	return nil
+}
+
+// fileImports parses the imports and the build constraint of a Go file, including the one
+// implied by its name. The constraint is nil if the file is always built.
+func fileImports(path string) ([]string, constraint.Expr, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, nil, err
+	}
+	expr := nameConstraint(filepath.Base(path))
+	for _, group := range f.Comments {
+		if group.Pos() > f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+			build, err := constraint.Parse(c.Text)
+			if err != nil {
+				return nil, nil, err
+			}
+			if expr == nil {
+				expr = build
+			} else {
+				expr = &constraint.AndExpr{X: build, Y: expr}
+			}
+		}
+	}
+	var imports []string
+	for _, spec := range f.Imports {
+		if imp, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, imp)
+		}
+	}
+	return imports, expr, nil
+}
+
+// importConstraints returns, for every import of the package, the build constraint gating
+// it: the empty string if a file without constraint imports it, or else the disjunction of
+// the constraints of the importing files. Files which cannot be parsed are ignored.
+func importConstraints(p Package) map[string]string {
+	exprs := make(map[string][]constraint.Expr)
+	for _, files := range [][]string{p.GoFiles, p.CgoFiles, p.TestGoFiles, p.XTestGoFiles} {
+		for _, name := range files {
+			imports, expr, err := fileImports(filepath.Join(p.Dir, name))
+			if err != nil {
+				continue
+			}
+			for _, imp := range imports {
+				exprs[imp] = append(exprs[imp], expr)
+			}
+		}
+	}
+	res := make(map[string]string, len(exprs))
+	for imp, list := range exprs {
+		var or constraint.Expr
+		seen := make(map[string]bool)
+		for _, expr := range list {
+			if expr == nil {
+				or = nil
+				break
+			}
+			if seen[expr.String()] {
+				continue
+			}
+			seen[expr.String()] = true
+			if or == nil {
+				or = expr
+			} else {
+				or = &constraint.OrExpr{X: or, Y: expr}
+			}
+		}
+		if or != nil {
+			res[imp] = or.String()
+		}
+	}
+	return res
+}
+
+// pathConstraints returns, aligned with the paths, the build constraint of the edge leading
+// to every package of a path, or the empty string for the first package and unconstrained
+// edges.
+func pathConstraints(packages map[string]Package, paths [][]string) [][]string {
+	cache := make(map[string]map[string]string)
+	res := make([][]string, len(paths))
+	for i, p := range paths {
+		res[i] = make([]string, len(p))
+		for j := 1; j < len(p); j++ {
+			from := p[j-1]
+			if _, ok := cache[from]; !ok {
+				cache[from] = importConstraints(packages[from])
+			}
+			res[i][j] = cache[from][p[j]]
+		}
+	}
+	return res
+}
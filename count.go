@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// PathCount is the number of paths from the root to the target.
+type PathCount struct {
+	Paths string `json:"paths"`
+	// LowerBound is set when import cycles, which only exist through test imports, were
+	// broken to count, so that some paths are not counted.
+	LowerBound bool `json:"lower_bound,omitempty"`
+}
+
+// checkCountOnly returns an error if --count-only is given with options filtering or
+// truncating the paths, which only apply to enumerated paths.
+func (o Opts) checkCountOnly() error {
+	if !o.CountOnly {
+		return nil
+	}
+	conflicts := map[string]bool{
+		"--depth":            o.Depth > 0,
+		"--tail-depth":       o.TailDepth > 0,
+		"--via":              o.Via != "",
+		"--test-only":        o.TestOnly,
+		"--first-party-only": o.FirstPartyOnly,
+	}
+	var flags []string
+	for flag, set := range conflicts {
+		if set {
+			flags = append(flags, flag)
+		}
+	}
+	if len(flags) > 0 {
+		sort.Strings(flags)
+		return fmt.Errorf("--count-only cannot be used with %s", strings.Join(flags, ", "))
+	}
+	return nil
+}
+
+// countPaths counts the paths from start to end without enumerating them, by dynamic
+// programming in reverse topological order. Edges closing a cycle in the depth-first search
+// are dropped, the count is then a lower bound.
+func countPaths(start string, end string, forward map[string][]string) *PathCount {
+	live := make(map[string]bool)
+	reversed := make(map[string][]string)
+	for from, tos := range forward {
+		for _, to := range tos {
+			reversed[to] = append(reversed[to], from)
+		}
+	}
+	for pkg := range reachable(end, reversed, nil) {
+		live[pkg] = true
+	}
+
+	const (
+		white = iota
+		gray
+		black
+	)
+	color := make(map[string]int)
+	counts := make(map[string]*big.Int)
+	res := &PathCount{Paths: "0"}
+	if !live[start] {
+		return res
+	}
+	type frame struct {
+		node  string
+		next  int
+		count *big.Int
+	}
+	color[start] = gray
+	stack := []*frame{{node: start, count: new(big.Int)}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if top.node == end {
+			top.count.SetInt64(1)
+			top.next = len(forward[top.node])
+		}
+		if top.next < len(forward[top.node]) {
+			next := forward[top.node][top.next]
+			top.next++
+			switch {
+			case !live[next]:
+			case color[next] == gray:
+				res.LowerBound = true
+			case color[next] == black:
+				top.count.Add(top.count, counts[next])
+			default:
+				color[next] = gray
+				stack = append(stack, &frame{node: next, count: new(big.Int)})
+			}
+			continue
+		}
+		color[top.node] = black
+		counts[top.node] = top.count
+		stack = stack[:len(stack)-1]
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			parent.count.Add(parent.count, top.count)
+		}
+	}
+	res.Paths = counts[start].String()
+	return res
+}
+
+func printPathCount(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	if res.PathCount.LowerBound {
+		fmt.Fprintf(w, "at least %s path(s), import cycles through test imports were broken to count\n", res.PathCount.Paths)
+	} else {
+		fmt.Fprintf(w, "%s path(s)\n", res.PathCount.Paths)
+	}
+	return nil
+}
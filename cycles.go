@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type cyclesCommand struct {
+	FirstParty bool `long:"first-party" description:"only report cycles among packages of the main module"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+func (c *cyclesCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	// go list -test fails on the import cycles of tests, which are the ones to report.
+	opts := *c.opts
+	opts.KeepGoing = true
+	g, err := loadGraph(opts)
+	if err != nil {
+		return err
+	}
+	forward := g.forward
+	if c.FirstParty {
+		forward = make(map[string][]string)
+		isMain := func(pkg string) bool {
+			m := g.pkgMap[pkg].Module
+			return m != nil && m.Main
+		}
+		for from, tos := range g.forward {
+			if !isMain(from) {
+				continue
+			}
+			for _, to := range tos {
+				if isMain(to) {
+					forward[from] = append(forward[from], to)
+				}
+			}
+		}
+	}
+	infof("Detecting import cycles...")
+	cycles := findCycles(forward)
+	infof("Successfully detected %d import cycles", len(cycles))
+
+	return writeReport(c.parser, *c.opts, "cycles", Result{Root: g.root, Cycles: cycles}, func(w io.Writer) error {
+		return printCycles(w, cycles)
+	})
+}
+
+// findCycles returns one cycle for each strongly connected component with more than one
+// package, or a package importing itself, using Tarjan's algorithm. Each cycle starts and
+// ends with the lexicographically smallest package of the component.
+func findCycles(forward map[string][]string) [][]string {
+	nodes := make([]string, 0, len(forward))
+	for node := range forward {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+	var strongConnect func(node string)
+	strongConnect = func(node string) {
+		index[node] = len(index)
+		low[node] = index[node]
+		stack = append(stack, node)
+		onStack[node] = true
+		for _, next := range forward[node] {
+			if _, ok := index[next]; !ok {
+				strongConnect(next)
+				if low[next] < low[node] {
+					low[node] = low[next]
+				}
+			} else if onStack[next] && index[next] < low[node] {
+				low[node] = index[next]
+			}
+		}
+		if low[node] == index[node] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == node {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+	for _, node := range nodes {
+		if _, ok := index[node]; !ok {
+			strongConnect(node)
+		}
+	}
+
+	cycles := make([][]string, 0)
+	for _, component := range components {
+		sort.Strings(component)
+		start := component[0]
+		if len(component) == 1 && !contains(forward[start], start) {
+			continue
+		}
+		members := make(map[string]bool, len(component))
+		for _, node := range component {
+			members[node] = true
+		}
+		cycles = append(cycles, shortestCycle(start, forward, members))
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// shortestCycle returns the shortest cycle from start back to itself within members.
+func shortestCycle(start string, forward map[string][]string, members map[string]bool) []string {
+	prev := make(map[string]string)
+	queue := []string{start}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range forward[node] {
+			if !members[next] {
+				continue
+			}
+			if next == start {
+				cycle := []string{start}
+				for n := node; n != start; n = prev[n] {
+					cycle = append(cycle, n)
+				}
+				cycle = append(cycle, start)
+				return reversePaths([][]string{cycle})[0]
+			}
+			if _, ok := prev[next]; !ok {
+				prev[next] = node
+				queue = append(queue, next)
+			}
+		}
+	}
+	return []string{start, start}
+}
+
+func printCycles(w io.Writer, cycles [][]string) error {
+	fmt.Fprintln(w, "# cycles")
+	if len(cycles) == 0 {
+		fmt.Fprintln(w, "no import cycle found")
+		return nil
+	}
+	for _, cycle := range cycles {
+		for _, item := range cycle {
+			fmt.Fprintln(w, item)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// listFields are the Package fields requested from go list -json, leaving out the large
+// ones like Deps and the file lists the analysis does not read.
+const listFields = "ImportPath,Standard,Module,Imports,TestImports,Error,Name,ForTest,Dir,GoFiles,CgoFiles,TestGoFiles,XTestGoFiles"
+
+// chunkPool recycles the buffers holding the JSON object of one package.
+var chunkPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+type chunk struct {
+	i   int
+	buf *bytes.Buffer
+	p   Package
+	err error
+}
+
+// decodePackages decodes the stream of JSON objects printed by go list -json in parallel.
+// go list prints every object from a "{" line to a "}" line at column 0, so the stream is
+// split on those lines and the objects are unmarshaled by a goroutine per CPU, keeping
+// their order.
+func decodePackages(r io.Reader) ([]Package, error) {
+	chunks := make(chan chunk)
+	decoded := make(chan chunk)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				c.err = json.Unmarshal(c.buf.Bytes(), &c.p)
+				c.buf.Reset()
+				chunkPool.Put(c.buf)
+				c.buf = nil
+				decoded <- c
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(decoded)
+	}()
+
+	var readErr error
+	go func() {
+		defer close(chunks)
+		br := bufio.NewReaderSize(r, 1<<16)
+		buf := chunkPool.Get().(*bytes.Buffer)
+		lineStart := true
+		for i := 0; ; {
+			line, err := br.ReadSlice('\n')
+			buf.Write(line)
+			if err == bufio.ErrBufferFull {
+				lineStart = false
+				continue
+			}
+			if lineStart && len(line) > 0 && line[0] == '}' {
+				chunks <- chunk{i: i, buf: buf}
+				i++
+				buf = chunkPool.Get().(*bytes.Buffer)
+			}
+			lineStart = true
+			if err != nil {
+				if err != io.EOF {
+					readErr = err
+				} else if len(bytes.TrimSpace(buf.Bytes())) > 0 {
+					readErr = io.ErrUnexpectedEOF
+				}
+				return
+			}
+		}
+	}()
+
+	var packages []Package
+	var firstErr chunk
+	for c := range decoded {
+		for len(packages) <= c.i {
+			packages = append(packages, Package{})
+		}
+		packages[c.i] = c.p
+		if c.err != nil && (firstErr.err == nil || c.i < firstErr.i) {
+			firstErr = c
+		}
+	}
+	if firstErr.err != nil {
+		return nil, firstErr.err
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return packages, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// PathDiff is the change of the import chains from a root to a target between the graph
+// saved by --save-graph and the loaded one.
+type PathDiff struct {
+	Target  string     `json:"target"`
+	Root    string     `json:"root"`
+	Added   [][]string `json:"added"`
+	Removed [][]string `json:"removed"`
+}
+
+type diffCommand struct {
+	Base string `long:"base" description:"graph saved by --save-graph to compare the loaded graph with" required:"yes"`
+	Args struct {
+		Targets []string `positional-arg-name:"target-pkg"`
+	} `positional-args:"yes" required:"yes"`
+	PathOpts `group:"Path Options"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// diffPaths returns the chains added and removed for every target and root of the results
+// of the base graph and of the loaded one, in the order of the loaded results.
+func diffPaths(before, after []Result) []PathDiff {
+	type key struct{ target, root string }
+	var keys []key
+	paths := make(map[key][2][][]string)
+	for i, results := range [][]Result{before, after} {
+		for _, res := range results {
+			k := key{target: res.Target, root: res.Root}
+			p, ok := paths[k]
+			if !ok {
+				keys = append(keys, k)
+			}
+			p[i] = res.Paths
+			paths[k] = p
+		}
+	}
+	diffs := make([]PathDiff, 0, len(keys))
+	for _, k := range keys {
+		p := paths[k]
+		diffs = append(diffs, PathDiff{Target: k.target, Root: k.root, Added: subtractPaths(p[1], p[0]), Removed: subtractPaths(p[0], p[1])})
+	}
+	return diffs
+}
+
+// subtractPaths returns the paths of a which are not in b.
+func subtractPaths(a, b [][]string) [][]string {
+	in := make(map[string]bool, len(b))
+	for _, path := range b {
+		in[strings.Join(path, "->")] = true
+	}
+	res := make([][]string, 0)
+	for _, path := range a {
+		if !in[strings.Join(path, "->")] {
+			res = append(res, path)
+		}
+	}
+	return res
+}
+
+// diffResults explains the targets in the graph. Targets missing from the graph have no
+// chains rather than failing, since the diff is about them appearing or disappearing.
+func diffResults(opts Opts, g *graph, targets []string) ([]Result, error) {
+	results, err := explainGraph(opts, g, targets)
+	if err == errNoTarget {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range results {
+		if res.hasReport() || res.Edges != nil {
+			return nil, errors.New("diff only compares import chains, not reports or subgraphs")
+		}
+	}
+	return results, nil
+}
+
+func (c *diffCommand) Execute(args []string) error {
+	opts := *c.opts
+	opts.PathOpts = c.PathOpts
+	infof("Loading base graph from %s...", c.Base)
+	l, err := loadSnapshot(c.Base)
+	if err != nil {
+		return err
+	}
+	base, err := buildGraph(opts, l)
+	if err != nil {
+		return err
+	}
+	g, err := loadGraph(opts)
+	if err != nil {
+		return err
+	}
+	before, err := diffResults(opts, base, c.Args.Targets)
+	if err != nil {
+		return err
+	}
+	after, err := diffResults(opts, g, c.Args.Targets)
+	if err != nil {
+		return err
+	}
+	if len(before) == 0 && len(after) == 0 {
+		return errNoTarget
+	}
+	diffs := diffPaths(before, after)
+
+	return writeReport(c.parser, opts, "diff", Result{Root: g.root, Diff: diffs}, func(w io.Writer) error {
+		return printDiff(w, diffs)
+	})
+}
+
+func printDiff(w io.Writer, diffs []PathDiff) error {
+	for _, d := range diffs {
+		fmt.Fprintf(w, "# %s\n", d.Target)
+		if len(d.Added) == 0 && len(d.Removed) == 0 {
+			fmt.Fprintln(w, "import chains unchanged")
+			fmt.Fprintln(w)
+			continue
+		}
+		for _, change := range []struct {
+			sign  string
+			paths [][]string
+		}{{"-", d.Removed}, {"+", d.Added}} {
+			for _, path := range change.paths {
+				for _, pkg := range path {
+					fmt.Fprintf(w, "%s %s\n", change.sign, pkg)
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	}
+	return nil
+}
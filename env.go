@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// envPrefix prefixes the environment variables setting options, e.g. GOMODWHY_PATTERN for
+// --pattern or GOMODWHY_INCLUDE_TEST for --include-test.
+const envPrefix = "GOMODWHY_"
+
+// envName returns the environment variable setting the option.
+func envName(opt *flags.Option) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(opt.LongName, "-", "_"))
+}
+
+// envArgs returns the options of cmd set by GOMODWHY_ environment variables as command line
+// arguments to put before the actual ones, after the name of the command, leaving out the
+// options given among them. Repeatable options take comma-separated values.
+func envArgs(parser *flags.Parser, cmd *flags.Command, args []string) ([]string, error) {
+	given := givenOptions(cmd, args)
+	var res []string
+	for _, opt := range commandOptions(parser, cmd) {
+		if opt.LongName == "" || given[opt] {
+			continue
+		}
+		value, ok := os.LookupEnv(envName(opt))
+		if !ok {
+			continue
+		}
+		values := []string{value}
+		if opt.Field().Type.Kind() == reflect.Slice {
+			values = splitList(values)
+		}
+		for _, v := range values {
+			arg, err := optionArg(opt, v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", envName(opt), err)
+			}
+			if arg != "" {
+				res = append(res, arg)
+			}
+		}
+	}
+	return res, nil
+}
+
+// commandOptions returns the options of the main command, and of cmd if it is another one.
+func commandOptions(parser *flags.Parser, cmd *flags.Command) []*flags.Option {
+	var opts []*flags.Option
+	var walk func(groups []*flags.Group)
+	walk = func(groups []*flags.Group) {
+		for _, g := range groups {
+			opts = append(opts, g.Options()...)
+			walk(g.Groups())
+		}
+	}
+	walk(parser.Groups())
+	if cmd != parser.Command {
+		walk(cmd.Groups())
+	}
+	return opts
+}
+
+// anyOption reports whether the main command or any other one has the option of the long
+// name.
+func anyOption(parser *flags.Parser, name string) bool {
+	if parser.FindOptionByLongName(name) != nil {
+		return true
+	}
+	for _, cmd := range parser.Commands() {
+		if cmd.FindOptionByLongName(name) != nil {
+			return true
+		}
+	}
+	return false
+}
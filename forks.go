@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Fork is a module on the paths replaced by a local directory or by another module, with
+// the paths depending on the replacement.
+type Fork struct {
+	Module  string     `json:"module"`
+	Version string     `json:"version,omitempty"`
+	Replace string     `json:"replace"`
+	Local   bool       `json:"local"`
+	Paths   [][]string `json:"paths"`
+}
+
+// findForks returns the modules on the paths replaced by a local directory or a fork. A
+// replacement by another version of the same module is not a fork.
+func findForks(res Result) []Fork {
+	index := make(map[string]int)
+	forks := make([]Fork, 0)
+	for _, p := range res.Paths {
+		seen := make(map[string]bool)
+		for _, pkg := range p {
+			m := res.packages[pkg].Module
+			if m == nil || m.Replace == nil || (m.Replace.Version != "" && m.Replace.Path == m.Path) || seen[m.Path] {
+				continue
+			}
+			seen[m.Path] = true
+			i, ok := index[m.Path]
+			if !ok {
+				i = len(forks)
+				index[m.Path] = i
+				forks = append(forks, Fork{Module: m.Path, Version: m.Version, Replace: res.replacementOf(pkg), Local: m.Replace.Version == ""})
+			}
+			forks[i].Paths = append(forks[i].Paths, p)
+		}
+	}
+	sort.Slice(forks, func(i, j int) bool { return forks[i].Module < forks[j].Module })
+	return forks
+}
+
+func printForks(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	if len(res.Forks) == 0 {
+		fmt.Fprintln(w, "no forked module on the import chains")
+		return nil
+	}
+	for _, f := range res.Forks {
+		kind := "fork"
+		if f.Local {
+			kind = "local"
+		}
+		fmt.Fprintf(w, "\n## %s => %s (%s, %d path(s))\n", ModuleChain{Module: f.Module, Version: f.Version}.label(), f.Replace, kind, len(f.Paths))
+		for i, p := range f.Paths {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			for _, pkg := range p {
+				fmt.Fprintln(w, pkg)
+			}
+		}
+	}
+	return nil
+}
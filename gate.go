@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// exitFound is the exit code of a run failed by --fail-if-found or --fail-if-missing, told
+// apart from the exit code 1 of errors.
+const exitFound = 2
+
+// errFound is returned by a run failed by --fail-if-found or --fail-if-missing once the
+// reason is printed, for main to exit with exitFound after the output is closed.
+var errFound = errors.New("run failed by --fail-if-found or --fail-if-missing")
+
+// checkFound returns an error naming the targets an import chain was found to with
+// --fail-if-found, and the ones none was found to with --fail-if-missing. A target is found
+// if any of the roots reaches it.
+func (o Opts) checkFound(results []Result) error {
+	if !o.FailIfFound && !o.FailIfMissing {
+		return nil
+	}
+	var targets []string
+	found := make(map[string]bool)
+	for _, res := range results {
+		if _, ok := found[res.Target]; !ok {
+			targets = append(targets, res.Target)
+		}
+		found[res.Target] = found[res.Target] || res.reached
+	}
+	var present, missing []string
+	for _, t := range targets {
+		if found[t] {
+			present = append(present, t)
+		} else {
+			missing = append(missing, t)
+		}
+	}
+	if o.FailIfFound && len(present) > 0 {
+		return fmt.Errorf("import chain found to %s", strings.Join(present, ", "))
+	}
+	if o.FailIfMissing && len(missing) > 0 {
+		return fmt.Errorf("no import chain found to %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -1,5 +1,7 @@
 module github.com/ycydsxy/gomodwhy
 
-go 1.16
+go 1.18
 
 require github.com/jessevdk/go-flags v1.6.1
+
+require golang.org/x/sys v0.21.0 // indirect
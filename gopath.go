@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+)
+
+// gopathMode reports whether the go command runs in GOPATH mode, without main module.
+func gopathMode() (bool, error) {
+	output, err := goCommand("env", "GOMOD").Output()
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(output)) == "", nil
+}
+
+// repoRoot guesses the repository root of a GOPATH import path, which stands in for its
+// module: the first three elements on well-known hosts, the whole path otherwise. Packages
+// vendored in a GOPATH repository are attributed to the repository they come from.
+func repoRoot(importPath string) string {
+	if i := strings.LastIndex(importPath, "/vendor/"); i >= 0 {
+		importPath = importPath[i+len("/vendor/"):]
+	}
+	elems := strings.Split(importPath, "/")
+	switch elems[0] {
+	case "github.com", "gitlab.com", "bitbucket.org", "golang.org", "gopkg.in":
+		if len(elems) > 3 {
+			return strings.Join(elems[:3], "/")
+		}
+	}
+	return importPath
+}
+
+// assignGOPATHModules gives GOPATH packages a module derived from their repository root, the
+// repository of the root package being the main module, so that first-party and third-party
+// code are told apart like in module mode.
+func assignGOPATHModules(packages []Package, root string) {
+	mainRepo := repoRoot(root)
+	for i, p := range packages {
+		if p.Standard || p.Module != nil {
+			continue
+		}
+		repo := repoRoot(p.ImportPath)
+		main := !strings.Contains(p.ImportPath, "/vendor/") && (p.ImportPath == mainRepo || strings.HasPrefix(p.ImportPath, mainRepo+"/"))
+		if main {
+			repo = mainRepo
+		}
+		packages[i].Module = &Module{Path: repo, Main: main}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// GoVersionReport lists the modules whose go directive is newer than the main module's one.
+type GoVersionReport struct {
+	Main    string        `json:"main"`
+	Modules []GoDirective `json:"modules"`
+}
+
+// GoDirective is a module of the build list with its go directive and the shortest
+// requirement chain from the main module.
+type GoDirective struct {
+	Module    string   `json:"module"`
+	GoVersion string   `json:"go_version"`
+	Path      []string `json:"path"`
+}
+
+type goVersionCommand struct {
+	parser *flags.Parser
+	opts   *Opts
+}
+
+var goVersionRegexp = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?(.*)$`)
+
+// compareGoVersions compares go versions like 1.21, 1.21.3 and 1.21rc1, a pre-release
+// being older than the release.
+func compareGoVersions(a, b string) int {
+	ma, mb := goVersionRegexp.FindStringSubmatch(a), goVersionRegexp.FindStringSubmatch(b)
+	if ma == nil || mb == nil {
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	}
+	for i := 1; i <= 3; i++ {
+		x, _ := strconv.Atoi(ma[i])
+		y, _ := strconv.Atoi(mb[i])
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case ma[4] == mb[4]:
+		return 0
+	case ma[4] == "":
+		return 1
+	case mb[4] == "":
+		return -1
+	case ma[4] < mb[4]:
+		return -1
+	}
+	return 1
+}
+
+// goVersions returns the modules of the build list needing a newer go version than the main
+// module, the newest first.
+func goVersions(modules []Module, modGraph map[string][]string) *GoVersionReport {
+	report := &GoVersionReport{Modules: []GoDirective{}}
+	var mainModule string
+	for _, m := range modules {
+		if m.Main {
+			mainModule, report.Main = m.Path, m.GoVersion
+		}
+	}
+	for _, m := range modules {
+		if m.Main || m.GoVersion == "" || compareGoVersions(m.GoVersion, report.Main) <= 0 {
+			continue
+		}
+		d := GoDirective{Module: m.Path + "@" + m.Version, GoVersion: m.GoVersion, Path: []string{}}
+		if paths := shortestPaths(mainModule, d.Module, modGraph, 0); len(paths) > 0 {
+			d.Path = paths[0]
+		}
+		report.Modules = append(report.Modules, d)
+	}
+	sort.Slice(report.Modules, func(i, j int) bool {
+		a, b := report.Modules[i], report.Modules[j]
+		if c := compareGoVersions(a.GoVersion, b.GoVersion); c != 0 {
+			return c > 0
+		}
+		return a.Module < b.Module
+	})
+	return report
+}
+
+func (c *goVersionCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	infof("Executing go list command to get the build list...")
+	modules, err := listModules("all")
+	if err != nil {
+		return err
+	}
+	modGraph, err := runGoModGraph()
+	if err != nil {
+		return err
+	}
+	report := goVersions(modules, modGraph)
+
+	var root string
+	for _, m := range modules {
+		if m.Main {
+			root = m.Path
+		}
+	}
+	return writeReport(c.parser, *c.opts, "goversion", Result{Root: root, GoVersion: report}, func(w io.Writer) error {
+		return printGoVersions(w, report)
+	})
+}
+
+func printGoVersions(w io.Writer, report *GoVersionReport) error {
+	fmt.Fprintln(w, "# goversion")
+	fmt.Fprintf(w, "main module: go %s\n", report.Main)
+	if len(report.Modules) == 0 {
+		fmt.Fprintln(w, "no dependency requires a newer go version")
+		return nil
+	}
+	fmt.Fprintf(w, "modules requiring a newer go version (%d):\n", len(report.Modules))
+	for _, m := range report.Modules {
+		fmt.Fprintf(w, "  %s go %s\n", m.Module, m.GoVersion)
+		for _, node := range m.Path {
+			fmt.Fprintf(w, "    %s\n", node)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// licenseFiles are the file names searched for a license at the root of a module.
+var licenseFiles = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "LICENCE", "LICENCE.md", "COPYING", "COPYING.md", "COPYING.txt", "LICENSE-MIT", "LICENSE-APACHE"}
+
+// licensePatterns recognize a license by phrases of its text, tested in order.
+var licensePatterns = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"gnu affero general public license"}},
+	{"LGPL-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", []string{"gnu lesser general public license"}},
+	{"GPL-3.0", []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", []string{"gnu general public license"}},
+	{"MPL-2.0", []string{"mozilla public license", "2.0"}},
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"ISC", []string{"permission to use, copy, modify, and/or distribute this software"}},
+	{"Unlicense", []string{"this is free and unencumbered software"}},
+	{"CC0-1.0", []string{"cc0 1.0 universal"}},
+}
+
+// detectLicense returns the SPDX identifier of the license found at the root of the module
+// directory, "unknown" if the license file is not recognized and "" without license file.
+func detectLicense(dir string) string {
+	for _, name := range licenseFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		text := strings.ToLower(strings.Join(strings.Fields(string(data)), " "))
+		for _, p := range licensePatterns {
+			matched := true
+			for _, phrase := range p.phrases {
+				if !strings.Contains(text, phrase) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return p.id
+			}
+		}
+		return "unknown"
+	}
+	return ""
+}
+
+// moduleLicenses detects the license of every module of the packages, keyed by module path.
+// The standard library is keyed by "std".
+func moduleLicenses(packages []Package) map[string]string {
+	res := map[string]string{"std": "BSD-3-Clause"}
+	for _, p := range packages {
+		if p.Module == nil || p.Module.Dir == "" {
+			continue
+		}
+		if _, ok := res[p.Module.Path]; !ok {
+			res[p.Module.Path] = detectLicense(p.Module.Dir)
+		}
+	}
+	return res
+}
+
+// licenseOf returns the license of the module of pkg, "" when licenses are not detected.
+func (res Result) licenseOf(pkg string) string {
+	if res.licenses == nil {
+		return ""
+	}
+	mod := res.moduleOf(pkg)
+	if mod == "" {
+		return ""
+	}
+	if lic := res.licenses[mod]; lic != "" {
+		return lic
+	}
+	return "none"
+}
+
+// pathLicenses returns the license of every package on the paths.
+func (res Result) pathLicenses() map[string]string {
+	licenses := make(map[string]string)
+	for _, p := range res.Paths {
+		for _, pkg := range p {
+			if lic := res.licenseOf(pkg); lic != "" {
+				licenses[pkg] = lic
+			}
+		}
+	}
+	return licenses
+}
+
+// LicenseGroup lists the modules on the paths under a license.
+type LicenseGroup struct {
+	License string   `json:"license"`
+	Modules []string `json:"modules"`
+}
+
+// summarizeLicenses rolls up the modules on the paths by license.
+func summarizeLicenses(res Result) []LicenseGroup {
+	modules := make(map[string]map[string]bool)
+	for _, p := range res.Paths {
+		for _, pkg := range p {
+			lic := res.licenseOf(pkg)
+			if lic == "" {
+				continue
+			}
+			if modules[lic] == nil {
+				modules[lic] = make(map[string]bool)
+			}
+			modules[lic][res.moduleOf(pkg)] = true
+		}
+	}
+	groups := make([]LicenseGroup, 0, len(modules))
+	for lic, mods := range modules {
+		g := LicenseGroup{License: lic}
+		for mod := range mods {
+			g.Modules = append(g.Modules, mod)
+		}
+		sort.Strings(g.Modules)
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].License < groups[j].License })
+	return groups
+}
+
+func printLicenseSummary(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	if len(res.LicenseSummary) == 0 {
+		fmt.Fprintln(w, "no import chain found")
+		return nil
+	}
+	for _, g := range res.LicenseSummary {
+		fmt.Fprintf(w, "%s (%d):\n", g.License, len(g.Modules))
+		for _, mod := range g.Modules {
+			fmt.Fprintf(w, "  %s\n", mod)
+		}
+	}
+	return nil
+}
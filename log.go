@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Log levels of the messages printed on stderr, stdout being kept for the results.
+const (
+	levelDebug = iota
+	levelInfo
+	levelWarn
+)
+
+var levelNames = map[string]int{"debug": levelDebug, "info": levelInfo, "warn": levelWarn}
+
+// logLevel is the lowest level of the messages logged, set once by setLogLevel.
+var logLevel = levelWarn
+
+// setLogLevel sets the log level of --log-level, lowered to info by --verbose.
+func setLogLevel(o Opts) {
+	if level, ok := levelNames[o.LogLevel]; ok {
+		logLevel = level
+	}
+	if o.Verbose && logLevel > levelInfo {
+		logLevel = levelInfo
+	}
+}
+
+func logf(level int, prefix string, format string, a ...interface{}) {
+	if level < logLevel {
+		return
+	}
+	fmt.Fprintf(os.Stderr, prefix+format+"\n", a...)
+}
+
+// debugf logs details only useful to debug a run.
+func debugf(format string, a ...interface{}) { logf(levelDebug, "debug: ", format, a...) }
+
+// infof logs the progress of the run.
+func infof(format string, a ...interface{}) { logf(levelInfo, "info: ", format, a...) }
+
+// warnf logs a problem which does not fail the run.
+func warnf(format string, a ...interface{}) { logf(levelWarn, "warning: ", format, a...) }
+
+// phase logs the start of a phase of the run at info level, and returns the function
+// logging its end with the time it took.
+func phase(format string, a ...interface{}) func() {
+	name := fmt.Sprintf(format, a...)
+	infof("%s...", name)
+	start := time.Now()
+	return func() {
+		infof("%s done in %v", name, time.Since(start).Round(time.Millisecond))
+	}
+}
@@ -1,31 +1,85 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 )
 
 type Package struct {
-	ImportPath  string
-	Imports     []string
-	TestImports []string
-}
-
-func runGoList(pattern string, includeTest bool) ([]Package, error) {
-	args := []string{"list", "-deps", "-json"}
-	// if includeTest {
-	// 	args = append(args, "-test")
-	// }
-	args = append(args, pattern)
-	cmd := exec.Command("go", args...)
+	ImportPath   string
+	Standard     bool
+	Module       *Module
+	Imports      []string
+	TestImports  []string
+	Error        *PackageError
+	Name         string
+	ForTest      string
+	Dir          string
+	GoFiles      []string
+	CgoFiles     []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+}
+
+type PackageError struct {
+	Err string
+}
+
+type Module struct {
+	Path      string
+	Version   string
+	Main      bool
+	Dir       string
+	GoVersion string
+	Replace   *Module
+	Retracted []string
+}
+
+// goDir is the directory go commands run in, the current directory if empty, and goModFile
+// the alternate go.mod they use. Both are set up once by Opts.prepare.
+var goDir, goModFile string
+
+func goCommand(args ...string) *exec.Cmd {
+	if goModFile != "" {
+		switch {
+		case args[0] == "list" || args[0] == "get":
+			args = append([]string{args[0], "-modfile=" + goModFile}, args[1:]...)
+		case args[0] == "mod" && args[1] == "graph":
+			args = append([]string{"mod", "graph", "-modfile=" + goModFile}, args[2:]...)
+		case args[0] == "mod" && args[1] == "edit":
+			args = append(args, goModFile)
+		}
+	}
+	cmd := exec.CommandContext(goContext, "go", args...)
+	cmd.Dir = goDir
+	return cmd
+}
+
+func runGoList(patterns []string, includeTest bool, listFlags, env []string) ([]Package, error) {
+	args := []string{"list", "-deps", "-json=" + listFields}
+	if includeTest {
+		args = append(args, "-test")
+	}
+	args = append(args, listFlags...)
+	args = append(args, patterns...)
+	cmd := goCommand(args...)
+	cmd.Env = append(os.Environ(), env...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return nil, err
@@ -42,35 +96,227 @@ func runGoList(pattern string, includeTest bool) ([]Package, error) {
 	var stderrBuf strings.Builder
 	go func() { io.Copy(&stderrBuf, stderr) }()
 
-	var packages []Package
-	dec := json.NewDecoder(stdout)
-	for {
-		var p Package
-		if err := dec.Decode(&p); err != nil {
-			if err == io.EOF {
-				break
-			}
-			return nil, fmt.Errorf("go list failed: %v\n\n%s\n%s", err, cmd.String(), stderrBuf.String())
+	packages, err := decodePackages(stdout)
+	if err != nil {
+		cmd.Wait()
+		if goContext.Err() == context.DeadlineExceeded {
+			return nil, errors.New("go list did not finish before the timeout")
 		}
-		packages = append(packages, p)
+		return nil, fmt.Errorf("go list failed: %v\n\n%s\n%s", err, cmd.String(), stderrBuf.String())
 	}
 	if err := cmd.Wait(); err != nil {
+		if goContext.Err() == context.DeadlineExceeded {
+			return nil, errors.New("go list did not finish before the timeout")
+		}
 		return nil, fmt.Errorf("go list failed: %v\n\n%s\n%s", err, cmd.String(), stderrBuf.String())
 	}
+	if includeTest {
+		packages = mergeTestPackages(packages)
+	}
 	return packages, nil
 }
 
+// testVariant strips the " [p.test]" suffix go list -test adds to packages compiled for a test.
+func testVariant(importPath string) string {
+	if i := strings.Index(importPath, " ["); i >= 0 {
+		return importPath[:i]
+	}
+	return importPath
+}
+
+// mergeTestPackages folds the packages synthesized by go list -test into the real ones: the
+// imports of "p [p.test]" and of the external test package "p_test [p.test]" which p does
+// not import become TestImports of p, and the "p.test" main packages are dropped. Only the
+// packages matched by --pattern have test variants: the TestImports go list reports for the
+// others are dropped, since go test would not build their tests. Packages keep the
+// post-order of their last variant, so the root package stays last.
+func mergeTestPackages(packages []Package) []Package {
+	merged := make(map[string]*Package)
+	last := make(map[string]int)
+	for i, p := range packages {
+		if p.ForTest == "" && p.Name == "main" && strings.HasSuffix(p.ImportPath, ".test") {
+			continue
+		}
+		owner := testVariant(p.ImportPath)
+		if p.ForTest != "" && strings.HasSuffix(p.Name, "_test") {
+			owner = p.ForTest
+		}
+		last[owner] = i
+		if p.ForTest == "" {
+			plain := p
+			if m, ok := merged[owner]; ok {
+				plain.TestImports = m.TestImports
+			} else {
+				plain.TestImports = nil
+			}
+			merged[owner] = &plain
+			continue
+		}
+		if _, ok := merged[owner]; !ok {
+			merged[owner] = &Package{ImportPath: owner}
+		}
+	}
+	for _, p := range packages {
+		if p.ForTest == "" {
+			continue
+		}
+		owner := testVariant(p.ImportPath)
+		if strings.HasSuffix(p.Name, "_test") {
+			owner = p.ForTest
+		}
+		m := merged[owner]
+		for _, imp := range p.Imports {
+			imp = testVariant(imp)
+			if imp != owner && !contains(m.Imports, imp) && !contains(m.TestImports, imp) {
+				m.TestImports = append(m.TestImports, imp)
+			}
+		}
+	}
+	owners := make([]string, 0, len(merged))
+	for owner := range merged {
+		owners = append(owners, owner)
+	}
+	sort.Slice(owners, func(i, j int) bool { return last[owners[i]] < last[owners[j]] })
+	res := make([]Package, 0, len(owners))
+	for _, owner := range owners {
+		res = append(res, *merged[owner])
+	}
+	return res
+}
+
 func buildForward(packages []Package, includeTest bool) map[string][]string {
 	forward := make(map[string][]string)
 	for _, p := range packages {
 		forward[p.ImportPath] = append(forward[p.ImportPath], p.Imports...)
 		if includeTest {
-			forward[p.ImportPath] = append(forward[p.ImportPath], p.TestImports...)
+			for _, imp := range p.TestImports {
+				if !contains(forward[p.ImportPath], imp) {
+					forward[p.ImportPath] = append(forward[p.ImportPath], imp)
+				}
+			}
 		}
 	}
 	return forward
 }
 
+// removePackages returns a copy of the forward graph without the given packages, or the
+// packages of the given modules.
+func removePackages(forward map[string][]string, packages []Package, avoid []string) map[string][]string {
+	removed := make(map[string]bool)
+	for _, name := range avoid {
+		removed[name] = true
+	}
+	for _, p := range packages {
+		if p.Module != nil && removed[p.Module.Path] {
+			removed[p.ImportPath] = true
+		}
+	}
+	res := make(map[string][]string, len(forward))
+	for from, tos := range forward {
+		if removed[from] {
+			continue
+		}
+		for _, to := range tos {
+			if !removed[to] {
+				res[from] = append(res[from], to)
+			}
+		}
+	}
+	return res
+}
+
+// removeEdges returns a copy of the forward graph without the given "from->to" edges.
+func removeEdges(forward map[string][]string, edges []string) (map[string][]string, error) {
+	removed := make(map[edge]bool)
+	for _, e := range edges {
+		parts := strings.SplitN(e, "->", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid edge %q, expected from->to", e)
+		}
+		removed[edge{from: strings.TrimSpace(parts[0]), to: strings.TrimSpace(parts[1])}] = true
+	}
+	res := make(map[string][]string, len(forward))
+	for from, tos := range forward {
+		for _, to := range tos {
+			if !removed[edge{from: from, to: to}] {
+				res[from] = append(res[from], to)
+			}
+		}
+	}
+	return res, nil
+}
+
+// excludedPackages returns the packages matching any of the --exclude patterns, globs where
+// `...` matches any string like in go package patterns.
+func excludedPackages(packages []Package, patterns []string) ([]string, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := globRegexp(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --exclude pattern %q: %v", pattern, err)
+		}
+		res = append(res, re)
+	}
+	var excluded []string
+	for _, p := range packages {
+		for _, re := range res {
+			if re.MatchString(p.ImportPath) {
+				excluded = append(excluded, p.ImportPath)
+				break
+			}
+		}
+	}
+	return excluded, nil
+}
+
+// splitList splits comma-separated values of a repeatable option.
+func splitList(values []string) []string {
+	var res []string
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				res = append(res, item)
+			}
+		}
+	}
+	return res
+}
+
+func packageMap(packages []Package) map[string]Package {
+	res := make(map[string]Package, len(packages))
+	for _, p := range packages {
+		res[p.ImportPath] = p
+	}
+	return res
+}
+
+// brokenPackages returns the packages go list reported an error for.
+func brokenPackages(packages []Package) []string {
+	var broken []string
+	for _, p := range packages {
+		if p.Error != nil {
+			broken = append(broken, p.ImportPath)
+		}
+	}
+	return broken
+}
+
+// testOnlyEdges returns the edges which only exist in test imports.
+func testOnlyEdges(packages []Package) map[edge]bool {
+	res := make(map[edge]bool)
+	for _, p := range packages {
+		for _, imp := range p.TestImports {
+			res[edge{from: p.ImportPath, to: imp}] = true
+		}
+	}
+	for _, p := range packages {
+		for _, imp := range p.Imports {
+			delete(res, edge{from: p.ImportPath, to: imp})
+		}
+	}
+	return res
+}
+
 func hasCycle(path []string, node string) bool {
 	for _, n := range path {
 		if n == node {
@@ -80,6 +326,15 @@ func hasCycle(path []string, node string) bool {
 	return false
 }
 
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
 func mergePaths(fromPath []string, toPath []string) []string {
 	merged := make([]string, len(fromPath)+len(toPath))
 	copy(merged, fromPath)
@@ -118,154 +373,1859 @@ func reversePaths(paths [][]string) [][]string {
 	return reversed
 }
 
-func allPaths(start string, end string, forward map[string][]string, depth int) [][]string {
+// nodeIndex interns package names into dense IDs, so that the path search works on
+// adjacency slices and paths of IDs instead of strings.
+type nodeIndex struct {
+	names []string
+	ids   map[string]int32
+}
+
+func newNodeIndex() *nodeIndex {
+	return &nodeIndex{ids: make(map[string]int32)}
+}
+
+func (x *nodeIndex) id(name string) int32 {
+	if id, ok := x.ids[name]; ok {
+		return id
+	}
+	id := int32(len(x.names))
+	x.names = append(x.names, name)
+	x.ids[name] = id
+	return id
+}
+
+// allPaths returns the paths from start to end, at most limit of them if limit is positive.
+// The first returned bool reports whether paths were dropped because of the limit, the
+// second whether the search stopped because the paths outgrew budget bytes, if positive.
+func allPaths(start string, end string, forward map[string][]string, depth int, limit int, workers int, budget int64) ([][]string, bool, bool) {
 	if depth <= 0 {
 		depth = math.MaxInt32
 	}
 
-	// Build reversed graph
-	reversedMap := make(map[string][]string)
+	// Build reversed graph, restricted to the packages reachable from start. The search walks
+	// back from end, so it would otherwise explore importers which never lead to start.
+	live := reachable(start, forward, nil)
+	index := newNodeIndex()
+	startID, endID := index.id(start), index.id(end)
+	var reversed [][]int32
 	for k, v := range forward {
+		if !live[k] {
+			continue
+		}
+		from := index.id(k)
 		for _, next := range v {
-			reversedMap[next] = append(reversedMap[next], k)
+			to := index.id(next)
+			for len(reversed) < len(index.names) {
+				reversed = append(reversed, nil)
+			}
+			reversed[to] = append(reversed[to], from)
 		}
 	}
+	for len(reversed) < len(index.names) {
+		reversed = append(reversed, nil)
+	}
 
 	// Find all paths from end to start in reversed graph
-	paths := doAllPaths(end, start, reversedMap, depth, map[string]*depthCache{})
+	cache := newPathCache(len(index.names), budget)
+	idPaths := parallelAllPaths(endID, startID, reversed, depth, limit, cache, workers)
+	if cache.overBudget() {
+		return nil, false, true
+	}
+	truncated := false
+	if limit > 0 && len(idPaths) > limit {
+		idPaths = idPaths[:limit]
+		truncated = true
+	}
+
+	if isInterrupted() {
+		truncated = true
+	}
+	atomic.AddInt64(&progress.paths, int64(len(idPaths)))
 
-	// Reverse paths to get from start to end
-	paths = reversePaths(paths)
+	// Reverse paths to get from start to end, converting IDs back to package names
+	paths := make([][]string, len(idPaths))
+	for i, p := range idPaths {
+		paths[i] = make([]string, len(p))
+		for j, id := range p {
+			paths[i][len(p)-1-j] = index.names[id]
+		}
+	}
+
+	sortPaths(paths)
+	return paths, truncated, false
+}
 
-	// Sort paths by length and lexicographically
+// sortPaths sorts paths by length and lexicographically.
+func sortPaths(paths [][]string) {
 	sort.Slice(paths, func(i, j int) bool {
 		if len(paths[i]) != len(paths[j]) {
 			return len(paths[i]) < len(paths[j])
 		}
 		return strings.Join(paths[i], "->") < strings.Join(paths[j], "->")
 	})
+}
+
+// sortPathsBy sorts paths already sorted by length in the given --sort order: lexical
+// ignores the length, module groups them by the first module out of the main module they
+// pass through and entrypoint by their last first-party package, by length within groups.
+func sortPathsBy(res Result, paths [][]string, order string) {
+	var key func(path []string) string
+	switch order {
+	case "lexical":
+		sort.Slice(paths, func(i, j int) bool {
+			return strings.Join(paths[i], "->") < strings.Join(paths[j], "->")
+		})
+		return
+	case "module":
+		key = func(path []string) string {
+			for _, pkg := range path {
+				if m := res.packages[pkg].Module; m == nil || !m.Main {
+					return moduleLabel(res, pkg)
+				}
+			}
+			return ""
+		}
+	case "entrypoint":
+		key = res.entryPackage
+	default:
+		return
+	}
+	keyed := make([]struct {
+		key  string
+		path []string
+	}, len(paths))
+	for i, p := range paths {
+		keyed[i].key, keyed[i].path = key(p), p
+	}
+	sort.SliceStable(keyed, func(i, j int) bool { return keyed[i].key < keyed[j].key })
+	for i := range keyed {
+		paths[i] = keyed[i].path
+	}
+}
+
+// shortestPaths returns all paths of minimal length from start to end. It runs a
+// bidirectional BFS, expanding level by level the smaller of the frontiers from start and
+// from end until they meet, so that it explores about the square root of the nodes a BFS
+// from start alone would in a wide graph. The paths are then joined at the meeting nodes
+// from their predecessors towards start and successors towards end.
+func shortestPaths(start string, end string, forward map[string][]string, depth int) [][]string {
+	if start == end {
+		return [][]string{{start}}
+	}
+	reversedMap := make(map[string][]string)
+	for k, v := range forward {
+		for _, next := range v {
+			reversedMap[next] = append(reversedMap[next], k)
+		}
+	}
+	fwd := newBFSSide(start)
+	bwd := newBFSSide(end)
+	var meet []string
+	for len(meet) == 0 {
+		if len(fwd.level) == 0 || len(bwd.level) == 0 || isInterrupted() {
+			return [][]string{}
+		}
+		side, other, adjacent := fwd, bwd, forward
+		if len(bwd.level) < len(fwd.level) {
+			side, other, adjacent = bwd, fwd, reversedMap
+		}
+		side.expand(adjacent)
+		// Every shortest path has exactly one node at the depth just reached, and the
+		// other side saw it if the path is no longer than both depths.
+		best := -1
+		for _, node := range side.level {
+			if d, ok := other.dist[node]; ok && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		for _, node := range side.level {
+			if d, ok := other.dist[node]; ok && d == best {
+				meet = append(meet, node)
+			}
+		}
+	}
+	sort.Strings(meet)
 
+	var paths [][]string
+	for _, m := range meet {
+		for _, head := range fwd.walk(m) {
+			for _, tail := range bwd.walk(m) {
+				path := mergePaths(head, reversePaths([][]string{tail})[0][1:])
+				paths = append(paths, path)
+			}
+		}
+	}
+	if depth > 0 {
+		paths = reversePaths(trimAndUnique(reversePaths(paths), depth))
+	}
+	sortPaths(paths)
 	return paths
 }
 
-type depthCache struct {
-	depth int
-	paths [][]string
+// bfsSide is one direction of the bidirectional BFS of shortestPaths: the distance of the
+// nodes reached from its origin, their neighbors one level closer to the origin, and the
+// last level reached.
+type bfsSide struct {
+	origin string
+	dist   map[string]int
+	preds  map[string][]string
+	level  []string
 }
 
-func (c *depthCache) get(depth int) ([][]string, bool) {
-	if c == nil || depth > c.depth {
-		return nil, false
+func newBFSSide(origin string) *bfsSide {
+	return &bfsSide{origin: origin, dist: map[string]int{origin: 0}, preds: make(map[string][]string), level: []string{origin}}
+}
+
+// expand reaches the next level through the adjacency map.
+func (b *bfsSide) expand(adjacent map[string][]string) {
+	var next []string
+	for _, node := range b.level {
+		atomic.AddInt64(&progress.nodes, 1)
+		for _, to := range adjacent[node] {
+			d, ok := b.dist[to]
+			if !ok {
+				d = b.dist[node] + 1
+				b.dist[to] = d
+				next = append(next, to)
+			}
+			if d == b.dist[node]+1 {
+				b.preds[to] = append(b.preds[to], node)
+			}
+		}
 	}
-	return trimAndUnique(c.paths, depth), true
+	b.level = next
 }
 
-func (c *depthCache) put(depth int, paths [][]string) {
-	if depth <= c.depth {
-		return
+// walk returns the shortest paths from the origin to node.
+func (b *bfsSide) walk(node string) [][]string {
+	if node == b.origin {
+		return [][]string{{node}}
 	}
-	c.depth = depth
-	c.paths = paths
+	var res [][]string
+	for _, prev := range b.preds[node] {
+		for _, path := range b.walk(prev) {
+			res = append(res, mergePaths(path, []string{node}))
+		}
+	}
+	return res
 }
 
-// doAllPaths returns all paths from start to end in forward graph.
-// Note: There is a premise that any path from the `start` node will eventually reach the `end` node.
-func doAllPaths(start string, end string, forward map[string][]string, depthLeft int, cache map[string]*depthCache) [][]string {
-	if start == end || depthLeft <= 0 {
-		return [][]string{{start}}
+// bestFirstLimit is the largest --max-paths for which the paths are searched best-first
+// instead of enumerated depth-first, the queue of partial paths outgrowing the depth-first
+// caches beyond it.
+const bestFirstLimit = 1000
+
+// firstPaths returns the limit shortest paths from start to end, with shortest only those
+// of minimal length. It is a best-first search over partial paths, ordered by their length
+// plus the distance left to end, so that the paths come out by increasing length and the
+// search stops as soon as enough are found. The returned bool reports whether paths were
+// dropped because of the limit.
+func firstPaths(start string, end string, forward map[string][]string, limit int, shortest bool) ([][]string, bool) {
+	// Distance to end of nodes able to reach it
+	reversedMap := make(map[string][]string)
+	for k, v := range forward {
+		for _, next := range v {
+			reversedMap[next] = append(reversedMap[next], k)
+		}
 	}
-	if len(forward[start]) == 0 {
-		return nil
+	dist := map[string]int{end: 0}
+	queue := []string{end}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, prev := range reversedMap[node] {
+			if _, ok := dist[prev]; !ok {
+				dist[prev] = dist[node] + 1
+				queue = append(queue, prev)
+			}
+		}
 	}
-	if paths, ok := cache[start].get(depthLeft); ok {
-		return paths
+	if _, ok := dist[start]; !ok {
+		return [][]string{}, false
 	}
-	res := make([][]string, 0)
-	for _, next := range forward[start] {
-		paths := doAllPaths(next, end, forward, depthLeft-1, cache)
-		var pathsToAppend [][]string
-		for _, path := range paths {
-			if hasCycle(path, start) {
+
+	// A partial path is a chain of hops back to start, queued in the bucket of its length
+	// plus the distance left.
+	type hop struct {
+		node string
+		prev *hop
+		len  int
+	}
+	onPath := func(h *hop, node string) bool {
+		for ; h != nil; h = h.prev {
+			if h.node == node {
+				return true
+			}
+		}
+		return false
+	}
+	buckets := make([][]*hop, dist[start]+1)
+	buckets[dist[start]] = []*hop{{node: start}}
+	paths := [][]string{}
+	truncated := false
+	for f := dist[start]; f < len(buckets); {
+		if len(buckets[f]) == 0 {
+			f++
+			continue
+		}
+		if shortest && len(paths) > 0 && f > len(paths[0])-1 {
+			break
+		}
+		h := buckets[f][len(buckets[f])-1]
+		buckets[f] = buckets[f][:len(buckets[f])-1]
+		if h.node == end {
+			if limit > 0 && len(paths) == limit {
+				truncated = true
+				break
+			}
+			path := make([]string, h.len+1)
+			for ; h != nil; h = h.prev {
+				path[h.len] = h.node
+			}
+			paths = append(paths, path)
+			atomic.AddInt64(&progress.paths, 1)
+			continue
+		}
+		if isInterrupted() {
+			truncated = true
+			break
+		}
+		atomic.AddInt64(&progress.nodes, 1)
+		for _, to := range forward[h.node] {
+			d, ok := dist[to]
+			if !ok || onPath(h, to) {
 				continue
 			}
-			pathsToAppend = append(pathsToAppend, mergePaths([]string{start}, path))
+			next := h.len + 1 + d
+			for len(buckets) <= next {
+				buckets = append(buckets, nil)
+			}
+			buckets[next] = append(buckets[next], &hop{node: to, prev: h, len: h.len + 1})
 		}
-		res = append(res, pathsToAppend...)
 	}
+	sortPaths(paths)
+	return paths, truncated
+}
 
-	if cache[start] == nil {
-		cache[start] = new(depthCache)
+// subgraph returns the edges participating in at least one path from start to end, sorted
+// lexicographically. With a positive depth, only edges within depth hops of end are kept.
+func subgraph(start string, end string, forward map[string][]string, depth int) [][2]string {
+	if depth <= 0 {
+		depth = math.MaxInt32
 	}
-	cache[start].put(depthLeft, res)
 
-	return res
-}
+	// Nodes reachable from start
+	reachable := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range forward[node] {
+			if !reachable[next] {
+				reachable[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
 
-func printPaths(target string, paths [][]string) {
-	fmt.Printf("# %s\n", target)
-	if len(paths) == 0 {
-		fmt.Println("no import chain found")
-		return
+	// Distance to end of nodes able to reach it
+	reversedMap := make(map[string][]string)
+	for k, v := range forward {
+		for _, next := range v {
+			reversedMap[next] = append(reversedMap[next], k)
+		}
 	}
-	for _, p := range paths {
-		for _, item := range p {
-			fmt.Println(item)
+	dist := map[string]int{end: 0}
+	queue = []string{end}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, prev := range reversedMap[node] {
+			if _, ok := dist[prev]; !ok {
+				dist[prev] = dist[node] + 1
+				queue = append(queue, prev)
+			}
 		}
-		fmt.Println()
 	}
-}
 
-type Opts struct {
-	Pattern     string `long:"pattern" short:"p" description:"go list package matching pattern" default:"."`
-	Depth       int    `long:"depth" short:"d" description:"dependency path depth limit, 0 for unlimited" default:"0"`
-	IncludeTest bool   `long:"include-test" short:"t" description:"include test dependencies"`
-	Verbose     bool   `long:"verbose" short:"v" description:"print verbose information"`
+	set := make(map[[2]string]struct{})
+	edges := make([][2]string, 0)
+	for from, v := range forward {
+		if !reachable[from] || from == end {
+			continue
+		}
+		for _, to := range v {
+			d, ok := dist[to]
+			if !ok || d+1 > depth {
+				continue
+			}
+			e := [2]string{from, to}
+			if _, ok := set[e]; ok {
+				continue
+			}
+			set[e] = struct{}{}
+			edges = append(edges, e)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
 }
 
-func (o Opts) Printf(format string, a ...interface{}) {
-	if o.Verbose {
-		fmt.Printf(format, a...)
+// reachable returns the nodes reachable from start, start included, without passing the
+// nodes in skip.
+func reachable(start string, forward map[string][]string, skip map[string]bool) map[string]bool {
+	visited := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range forward[node] {
+			if !visited[next] && !skip[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
 	}
+	return visited
 }
 
-func main() {
-	var opts Opts
-	parser := flags.NewParser(&opts, flags.Default)
-	parser.Name = "gomodwhy"
-	parser.Usage = "[options] <target-pkg>"
-
-	args, err := parser.Parse()
-	if err != nil {
-		os.Exit(1)
+// dominators returns the packages and modules every path from the root to the target passes
+// through, ordered by their distance from the root.
+func dominators(res Result, forward map[string][]string) *Dominators {
+	dom := &Dominators{Packages: []string{}, Modules: []string{}}
+	edges := subgraph(res.Root, res.Target, forward, 0)
+	if len(edges) == 0 {
+		return dom
 	}
 
-	if len(args) != 1 {
-		parser.WriteHelp(os.Stderr)
-		os.Exit(1)
+	// Distance from the root, used for ordering
+	dist := map[string]int{res.Root: 0}
+	queue := []string{res.Root}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range forward[node] {
+			if _, ok := dist[next]; !ok {
+				dist[next] = dist[node] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	less := func(a, b string) bool {
+		if dist[a] != dist[b] {
+			return dist[a] < dist[b]
+		}
+		return a < b
 	}
 
-	targetPkg := args[0]
-
-	opts.Printf("Executing go list command to get dependency information...\n")
-	packages, err := runGoList(opts.Pattern, opts.IncludeTest)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err.Error())
-		os.Exit(1)
+	candidates := make(map[string]bool)
+	modules := make(map[string]map[string]bool)
+	for _, e := range edges {
+		for _, pkg := range e {
+			if pkg == res.Root || pkg == res.Target {
+				continue
+			}
+			candidates[pkg] = true
+			if mod := res.moduleOf(pkg); mod != "" && mod != "std" {
+				if modules[mod] == nil {
+					modules[mod] = make(map[string]bool)
+				}
+				modules[mod][pkg] = true
+			}
+		}
 	}
-	if len(packages) == 0 {
-		fmt.Fprintf(os.Stderr, "no package found\n\n")
-		parser.WriteHelp(os.Stderr)
-		os.Exit(1)
+	for pkg := range candidates {
+		if !reachable(res.Root, forward, map[string]bool{pkg: true})[res.Target] {
+			dom.Packages = append(dom.Packages, pkg)
+		}
 	}
-	opts.Printf("Successfully got dependency information for %d packages\n", len(packages))
-	root := packages[len(packages)-1].ImportPath // go list use post-order traversal
+	sort.Slice(dom.Packages, func(i, j int) bool { return less(dom.Packages[i], dom.Packages[j]) })
 
-	opts.Printf("Building dependency graph...\n")
-	forwardMap := buildForward(packages, opts.IncludeTest)
-	opts.Printf("Dependency graph built successfully\n")
+	// The modules of the root and the target trivially gate the target
+	first := make(map[string]string)
+	for mod, pkgs := range modules {
+		if mod == res.moduleOf(res.Root) || mod == res.moduleOf(res.Target) {
+			continue
+		}
+		if !reachable(res.Root, forward, pkgs)[res.Target] {
+			dom.Modules = append(dom.Modules, mod)
+			for pkg := range pkgs {
+				if first[mod] == "" || less(pkg, first[mod]) {
+					first[mod] = pkg
+				}
+			}
+		}
+	}
+	sort.Slice(dom.Modules, func(i, j int) bool { return less(first[dom.Modules[i]], first[dom.Modules[j]]) })
+	return dom
+}
 
-	opts.Printf("Analyzing dependency paths...\n")
-	paths := allPaths(root, targetPkg, forwardMap, opts.Depth)
-	opts.Printf("Successfully analyzed %d dependency paths\n\n", len(paths))
-	printPaths(targetPkg, paths)
+// minCut returns a minimal set of first-party import edges whose removal disconnects the
+// root from the target, computed with Edmonds-Karp max-flow where only edges from packages of
+// the main module can be cut. Nil is returned if no such set exists.
+func minCut(res Result, forward map[string][]string) [][2]string {
+	const inf = math.MaxInt32
+	edges := subgraph(res.Root, res.Target, forward, 0)
+	capacity := make(map[[2]string]int)
+	adj := make(map[string][]string)
+	for _, e := range edges {
+		c := inf
+		if m := res.packages[e[0]].Module; m != nil && m.Main {
+			c = 1
+		}
+		capacity[e] = c
+		adj[e[0]] = append(adj[e[0]], e[1])
+		adj[e[1]] = append(adj[e[1]], e[0]) // residual edge
+	}
+
+	// bfs returns the predecessors on an augmenting path, or the residual reachable set.
+	bfs := func() map[string]string {
+		prev := map[string]string{res.Root: ""}
+		queue := []string{res.Root}
+		for len(queue) > 0 && prev[res.Target] == "" {
+			node := queue[0]
+			queue = queue[1:]
+			for _, next := range adj[node] {
+				if _, ok := prev[next]; ok || capacity[[2]string{node, next}] <= 0 {
+					continue
+				}
+				prev[next] = node
+				queue = append(queue, next)
+			}
+		}
+		return prev
+	}
+	for {
+		prev := bfs()
+		if _, ok := prev[res.Target]; !ok {
+			break
+		}
+		bottleneck := inf
+		for node := res.Target; node != res.Root; node = prev[node] {
+			if c := capacity[[2]string{prev[node], node}]; c < bottleneck {
+				bottleneck = c
+			}
+		}
+		if bottleneck == inf {
+			return nil
+		}
+		for node := res.Target; node != res.Root; node = prev[node] {
+			capacity[[2]string{prev[node], node}] -= bottleneck
+			capacity[[2]string{node, prev[node]}] += bottleneck
+		}
+	}
+
+	// Saturated edges from the residual reachable set to the rest form the cut
+	side := bfs()
+	cut := make([][2]string, 0)
+	for _, e := range edges {
+		_, from := side[e[0]]
+		_, to := side[e[1]]
+		if from && !to {
+			cut = append(cut, e)
+		}
+	}
+	return cut
+}
+
+// directImporters returns the packages reachable from the root which import the target.
+func directImporters(res Result, forward map[string][]string) *Importers {
+	imp := &Importers{FirstParty: []string{}, ThirdParty: []string{}, Standard: []string{}}
+	for pkg := range reachable(res.Root, forward, map[string]bool{res.Target: true}) {
+		if !contains(forward[pkg], res.Target) {
+			continue
+		}
+		p := res.packages[pkg]
+		switch {
+		case p.Module != nil && p.Module.Main:
+			imp.FirstParty = append(imp.FirstParty, pkg)
+		case p.Standard:
+			imp.Standard = append(imp.Standard, pkg)
+		default:
+			imp.ThirdParty = append(imp.ThirdParty, pkg)
+		}
+	}
+	sort.Strings(imp.FirstParty)
+	sort.Strings(imp.ThirdParty)
+	sort.Strings(imp.Standard)
+	return imp
+}
+
+// weight returns the packages and modules which are only in the build because of the target.
+func weight(res Result, forward map[string][]string) *Weight {
+	all := reachable(res.Root, forward, nil)
+	w := &Weight{Packages: []string{}, Modules: []string{}}
+	if !all[res.Target] {
+		return w
+	}
+	others := reachable(res.Root, forward, map[string]bool{res.Target: true})
+	remaining := make(map[string]bool)
+	for pkg := range others {
+		remaining[moduleLabel(res, pkg)] = true
+	}
+	modules := make(map[string]bool)
+	for pkg := range all {
+		if others[pkg] {
+			continue
+		}
+		w.Packages = append(w.Packages, pkg)
+		if mod := moduleLabel(res, pkg); !remaining[mod] && !modules[mod] {
+			modules[mod] = true
+			w.Modules = append(w.Modules, mod)
+		}
+	}
+	sort.Strings(w.Packages)
+	sort.Strings(w.Modules)
+	return w
+}
+
+// reverseDeps returns the packages transitively imported by the target grouped by module.
+// Packages which are not reachable from the root anymore without the target are exclusive.
+func reverseDeps(res Result, forward map[string][]string) []ModuleDeps {
+	deps := reachable(res.Target, forward, nil)
+	delete(deps, res.Target)
+	others := reachable(res.Root, forward, map[string]bool{res.Target: true})
+	byModule := make(map[string]*ModuleDeps)
+	for pkg := range deps {
+		mod := res.moduleOf(pkg)
+		if mod == "" {
+			mod = pkg
+		}
+		if byModule[mod] == nil {
+			byModule[mod] = &ModuleDeps{Module: mod}
+			if m := res.packages[pkg].Module; m != nil {
+				byModule[mod].Version = m.Version
+			}
+		}
+		byModule[mod].Packages = append(byModule[mod].Packages, PackageDep{ImportPath: pkg, Exclusive: !others[pkg]})
+	}
+	modules := make([]ModuleDeps, 0, len(byModule))
+	for _, m := range byModule {
+		sort.Slice(m.Packages, func(i, j int) bool { return m.Packages[i].ImportPath < m.Packages[j].ImportPath })
+		modules = append(modules, *m)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Module < modules[j].Module })
+	return modules
+}
+
+// viaSubgraph is like subgraph, but only keeps the edges on paths passing through a package
+// for which isVia returns true.
+func viaSubgraph(start string, end string, forward map[string][]string, depth int, isVia func(string) bool) [][2]string {
+	vias := make(map[string]struct{})
+	for _, e := range subgraph(start, end, forward, depth) {
+		for _, pkg := range e {
+			if isVia(pkg) {
+				vias[pkg] = struct{}{}
+			}
+		}
+	}
+	set := make(map[[2]string]struct{})
+	edges := make([][2]string, 0)
+	for via := range vias {
+		for _, e := range append(subgraph(start, via, forward, 0), subgraph(via, end, forward, depth)...) {
+			if _, ok := set[e]; !ok {
+				set[e] = struct{}{}
+				edges = append(edges, e)
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
+
+// filterPaths returns the paths containing at least one package for which match returns true.
+func filterPaths(paths [][]string, match func(string) bool) [][]string {
+	res := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		for _, pkg := range path {
+			if match(pkg) {
+				res = append(res, path)
+				break
+			}
+		}
+	}
+	return res
+}
+
+type depthCache struct {
+	depth int
+	paths [][]int32
+}
+
+func (c *depthCache) get(depth int) ([][]int32, bool) {
+	if c == nil || depth > c.depth {
+		return nil, false
+	}
+	return trimAndUniqueIDs(c.paths, depth), true
+}
+
+func (c *depthCache) put(depth int, paths [][]int32) {
+	if depth <= c.depth {
+		return
+	}
+	c.depth = depth
+	c.paths = paths
+}
+
+// trimAndUniqueIDs is trimAndUnique for paths of node IDs.
+func trimAndUniqueIDs(paths [][]int32, depth int) [][]int32 {
+	set := make(map[string]struct{})
+	res := make([][]int32, 0)
+	key := make([]byte, 0, 64)
+	for _, path := range paths {
+		if len(path) >= depth+1 {
+			path = path[:depth+1]
+		}
+		key = key[:0]
+		for _, id := range path {
+			key = append(key, byte(id), byte(id>>8), byte(id>>16), byte(id>>24))
+		}
+		if _, ok := set[string(key)]; ok {
+			continue
+		}
+		set[string(key)] = struct{}{}
+		res = append(res, path)
+	}
+	return res
+}
+
+// prependID returns a new path starting with node followed by path, or false if node is
+// already on path.
+func prependID(node int32, path []int32) ([]int32, bool) {
+	for _, id := range path {
+		if id == node {
+			return nil, false
+		}
+	}
+	merged := make([]int32, len(path)+1)
+	merged[0] = node
+	copy(merged[1:], path)
+	return merged, true
+}
+
+// pathCache holds the paths found from every node, shared by the goroutines enumerating
+// paths in parallel. It also accounts for the memory taken by the paths built, against the
+// budget of --max-memory if positive.
+type pathCache struct {
+	mu     sync.Mutex
+	nodes  []*depthCache
+	bytes  int64
+	budget int64
+}
+
+func newPathCache(nodes int, budget int64) *pathCache {
+	return &pathCache{nodes: make([]*depthCache, nodes), budget: budget}
+}
+
+// grow accounts for a path built by the search.
+func (c *pathCache) grow(path []int32) {
+	if c.budget > 0 {
+		atomic.AddInt64(&c.bytes, int64(24+4*len(path)))
+	}
+}
+
+// overBudget reports whether the paths built exceed the budget, the search then stops.
+func (c *pathCache) overBudget() bool {
+	return c.budget > 0 && atomic.LoadInt64(&c.bytes) > c.budget
+}
+
+func (c *pathCache) get(node int32, depth int) ([][]int32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nodes[node].get(depth)
+}
+
+func (c *pathCache) put(node int32, depth int, paths [][]int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.nodes[node] == nil {
+		c.nodes[node] = new(depthCache)
+	}
+	c.nodes[node].put(depth, paths)
+}
+
+// doAllPaths returns all paths from start to end in forward graph. With a positive limit,
+// enumeration stops once more than limit paths are found.
+// The depth-first search runs on an explicit stack so that deep graphs do not overflow the
+// goroutine stack, each frame standing for a recursive call on a node. A node already on the
+// stack, which only happens through the import cycles of tests, is not visited again: the
+// paths of the frames above it then depend on the path to them and are not cached.
+// Note: There is a premise that any path from the `start` node will eventually reach the `end` node.
+func doAllPaths(start int32, end int32, forward [][]int32, depthLeft int, limit int, cache *pathCache) [][]int32 {
+	type frame struct {
+		node      int32
+		depthLeft int
+		next      int // index of the next import of node to visit
+		low       int // lowest index of a frame whose node was not visited again below
+		res       [][]int32
+	}
+	// enter returns the paths of node if they are known without visiting its imports.
+	enter := func(node int32, depthLeft int) ([][]int32, bool) {
+		if node == end || depthLeft <= 0 {
+			return [][]int32{{node}}, true
+		}
+		if len(forward[node]) == 0 {
+			return nil, true
+		}
+		return cache.get(node, depthLeft)
+	}
+	if paths, ok := enter(start, depthLeft); ok {
+		return paths
+	}
+	stack := []*frame{{node: start, depthLeft: depthLeft, res: make([][]int32, 0)}}
+	onStack := map[int32]int{start: 0}
+	var paths [][]int32 // paths returned by the last finished frame
+	returned := false
+	for {
+		top := stack[len(stack)-1]
+		if returned {
+			returned = false
+			if (isInterrupted() || cache.overBudget()) && len(paths) > 1 {
+				// Merging large lists of cached paths can take long, keep one per import.
+				paths = paths[:1]
+			}
+			for _, path := range paths {
+				if merged, ok := prependID(top.node, path); ok {
+					cache.grow(merged)
+					top.res = append(top.res, merged)
+				}
+			}
+			if limit > 0 && len(top.res) > limit {
+				top.res = top.res[:limit+1]
+				top.next = len(forward[top.node])
+			}
+		}
+		if isInterrupted() || cache.overBudget() {
+			top.next = len(forward[top.node])
+		}
+		if top.next < len(forward[top.node]) {
+			next := forward[top.node][top.next]
+			top.next++
+			if i, ok := onStack[next]; ok {
+				if i < top.low {
+					top.low = i
+				}
+				continue
+			}
+			if paths, returned = enter(next, top.depthLeft-1); !returned {
+				atomic.AddInt64(&progress.nodes, 1)
+				onStack[next] = len(stack)
+				stack = append(stack, &frame{node: next, depthLeft: top.depthLeft - 1, low: len(stack), res: make([][]int32, 0)})
+			}
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		delete(onStack, top.node)
+		if top.low == len(stack) {
+			cache.put(top.node, top.depthLeft, top.res)
+		}
+		if len(stack) == 0 {
+			return top.res
+		}
+		if parent := stack[len(stack)-1]; top.low < parent.low {
+			parent.low = top.low
+		}
+		paths, returned = top.res, true
+	}
+}
+
+// parallelAllPaths is doAllPaths enumerating the paths through every import of start on
+// its own goroutine, at most workers at a time, sharing the cache. A start with a single
+// import is followed until the paths branch out.
+func parallelAllPaths(start int32, end int32, forward [][]int32, depthLeft int, limit int, cache *pathCache, workers int) [][]int32 {
+	if workers <= 1 || start == end || depthLeft <= 0 || len(forward[start]) == 0 {
+		return doAllPaths(start, end, forward, depthLeft, limit, cache)
+	}
+	if paths, ok := cache.get(start, depthLeft); ok {
+		return paths
+	}
+	nexts := forward[start]
+	results := make([][][]int32, len(nexts))
+	if len(nexts) == 1 {
+		results[0] = parallelAllPaths(nexts[0], end, forward, depthLeft-1, limit, cache, workers)
+	} else {
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		for i, next := range nexts {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, next int32) {
+				defer wg.Done()
+				results[i] = doAllPaths(next, end, forward, depthLeft-1, limit, cache)
+				<-sem
+			}(i, next)
+		}
+		wg.Wait()
+	}
+
+	res := make([][]int32, 0)
+	for _, paths := range results {
+		for _, path := range paths {
+			if merged, ok := prependID(start, path); ok {
+				cache.grow(merged)
+				res = append(res, merged)
+			}
+		}
+		if limit > 0 && len(res) > limit {
+			res = res[:limit+1]
+			break
+		}
+	}
+	cache.put(start, depthLeft, res)
+	return res
+}
+
+type Opts struct {
+	Pattern      []string        `long:"pattern" short:"p" description:"go list package matching pattern, comma-separated or repeated to run one go list per pattern concurrently" default:"."`
+	Mode         string          `long:"mode" description:"graph to query, package imports from go list or module requirements from go mod graph" choice:"package" choice:"module" default:"package"`
+	KeepGoing    bool            `long:"keep-going" description:"build the graph from the packages which load, marking the ones with errors, instead of failing"`
+	Chdir        string          `long:"chdir" short:"C" description:"run the go command in the given directory"`
+	Modfile      string          `long:"modfile" description:"alternate go.mod file used by the go command"`
+	Mod          string          `long:"mod" description:"module download mode passed to go list" choice:"vendor" choice:"mod" choice:"readonly"`
+	SaveGraph    string          `long:"save-graph" description:"save the loaded packages to the file, to be queried later with --load-graph"`
+	LoadGraph    string          `long:"load-graph" description:"query the packages saved by --save-graph instead of running the go command"`
+	Cache        bool            `long:"cache" description:"reuse the go list output cached under the user cache directory, keyed by go.mod, go.sum and the query"`
+	Tags         string          `long:"tags" description:"comma-separated build tags passed to go list"`
+	GOOS         string          `long:"goos" description:"target operating system passed to go list as GOOS"`
+	GOARCH       string          `long:"goarch" description:"target architecture passed to go list as GOARCH"`
+	Platforms    []string        `long:"platforms" description:"report on which of the given os/arch platforms each path exists, comma-separated or repeated"`
+	IncludeTools bool            `long:"include-tools" description:"include dependencies of tools.go files and go.mod tool directives, marking tool-only paths"`
+	IncludeTest  bool            `long:"include-test" short:"t" description:"include test dependencies"`
+	From         string          `long:"from" description:"start paths from the given package instead of the root package"`
+	Avoid        []string        `long:"avoid" description:"exclude paths through the given packages or modules, comma-separated or repeated"`
+	WithoutPkg   []string        `long:"without-pkg" description:"simulate removing the given packages or modules from the graph, comma-separated or repeated"`
+	WithoutEdge  []string        `long:"without-edge" description:"simulate removing the given from->to import edges from the graph, comma-separated or repeated"`
+	Exclude      []string        `long:"exclude" description:"drop the packages matching the go-style pattern, e.g. example.com/legacy/..., from the graph, repeated"`
+	Jobs         int             `long:"jobs" short:"j" description:"goroutines enumerating paths in parallel, and go list commands run concurrently, 0 for the number of CPUs" default:"0"`
+	Format       string          `long:"format" short:"f" description:"output format" choice:"text" choice:"json" choice:"dot" choice:"svg" choice:"mermaid" choice:"plantuml" choice:"d2" choice:"tree" choice:"html" choice:"csv" choice:"tsv" choice:"table" choice:"markdown" choice:"template" default:"text"`
+	Output       string          `long:"output" short:"o" hidden:"yes" description:"deprecated alias of --format" choice:"text" choice:"json" choice:"dot" choice:"svg" choice:"mermaid" choice:"plantuml" choice:"d2" choice:"tree" choice:"html" choice:"csv" choice:"tsv" choice:"table" choice:"markdown" choice:"template"`
+	Out          string          `long:"out" description:"write output to file, format is inferred from the extension unless --format is given on the command line"`
+	CPUProfile   string          `long:"cpuprofile" description:"write a CPU profile of the run to the file"`
+	MemProfile   string          `long:"memprofile" description:"write a heap profile at the end of the run to the file"`
+	Timeout      time.Duration   `long:"timeout" description:"bound the run, e.g. 30s: go list fails past it, the path search stops and prints the paths found so far"`
+	Config       string          `long:"config" description:"read default options from the YAML file instead of the .gomodwhy.yaml found in the current directory or its parents, empty for none"`
+	LogLevel     string          `long:"log-level" description:"lowest level of the messages logged on stderr, with the time taken by each phase from info on" choice:"debug" choice:"info" choice:"warn" default:"warn"`
+	Verbose      bool            `long:"verbose" short:"v" description:"log at info level and annotate the build constraints of the imports"`
+	PathOpts     `no-flag:"yes"` // copied from the Path Options group of the command explaining targets
+
+	fromModule string      // module to start from every package of, of a module@version pattern or a scan
+	stream     *pathStream // prints paths as they are found with --stream
+	forceCgo   bool        // load with CGO_ENABLED=1
+	args       []string    // command line arguments, options of the config file and environment included
+	cmdline    []string    // actual command line arguments
+}
+
+// PathOpts are the options of the path search and of its output, which only the commands
+// explaining targets take.
+type PathOpts struct {
+	Server         string   `long:"server" description:"send the query to a gomodwhy serve process listening on the given unix socket instead of loading the graph"`
+	IncludeStd     string   `long:"include-std" description:"standard library packages kept in the graph, target keeps only the standard library targets" choice:"all" choice:"target" choice:"none" default:"all"`
+	Depth          int      `long:"depth" short:"d" description:"keep the last N hops before the target of every path, 0 for unlimited" default:"0"`
+	TailDepth      int      `long:"tail-depth" description:"same as --depth, keep the last N hops before the target of every path" default:"0"`
+	TestOnly       bool     `long:"test-only" description:"only show paths which exist because of test imports, implies --include-test"`
+	ProdOnly       bool     `long:"prod-only" description:"only show paths without test imports"`
+	TargetsFile    string   `long:"targets-file" description:"read additional newline-separated targets from the file, - for stdin"`
+	TargetMatch    string   `long:"target-match" description:"how the target argument is matched against packages" choice:"exact" choice:"regex" choice:"glob" choice:"module" default:"exact"`
+	Module         bool     `long:"module" short:"m" description:"treat the target as a module path and explain all of its packages, same as --target-match module"`
+	FirstPartyOnly bool     `long:"first-party-only" description:"print the paths truncated after their first package out of the main module"`
+	Via            string   `long:"via" description:"only show paths passing through the given package or module"`
+	Shortest       bool     `long:"shortest" description:"only print the shortest path(s)"`
+	MaxPaths       int      `long:"max-paths" description:"stop enumeration after N paths, 0 for unlimited" default:"0"`
+	MaxMemory      byteSize `long:"max-memory" description:"memory budget of the paths, e.g. 2GB, printing their subgraph instead once they outgrow it"`
+	Dominators     bool     `long:"dominators" description:"only list the packages and modules every path to the target passes through"`
+	ExplainCut     bool     `long:"explain-cut" description:"suggest a minimal set of first-party imports to remove to drop the target"`
+	WhoImports     bool     `long:"who-imports" description:"only list the direct importers of the target"`
+	Sort           string   `long:"sort" description:"order of the paths" choice:"length" choice:"lexical" choice:"module" choice:"entrypoint" default:"length"`
+	Granularity    string   `long:"granularity" description:"path hop granularity, module collapses consecutive packages of the same module" choice:"package" choice:"module" default:"package"`
+	GroupBy        string   `long:"group-by" description:"group paths, by the last first-party package on them for entry" choice:"none" choice:"entry" default:"none"`
+	Weight         bool     `long:"weight" description:"report the packages and modules which are only in the build because of the target"`
+	Reverse        bool     `long:"reverse" description:"list what the target transitively imports, grouped by module, instead of why it is imported"`
+	VersionStatus  bool     `long:"version-status" description:"annotate packages of modules at a pseudo-version or a retracted version, checking retractions queries the module proxy"`
+	Licenses       bool     `long:"licenses" description:"annotate packages with the license of their module"`
+	Forks          bool     `long:"forks" description:"report the modules on the paths replaced by a local directory or a fork, with the paths depending on them"`
+	LicenseSummary bool     `long:"license-summary" description:"roll up the modules on the paths by license"`
+	Rank           bool     `long:"rank" description:"rank intermediate packages by the number of paths passing through them"`
+	CountOnly      bool     `long:"count-only" description:"only print the number of paths, counted without enumerating them"`
+	Summary        bool     `long:"summary" description:"print aggregate numbers instead of the paths"`
+	Subgraph       bool     `long:"subgraph" description:"print the union of edges on any path instead of enumerating paths"`
+	Template       string   `long:"template" description:"go text/template for template format"`
+	Compat         bool     `long:"compat" description:"print the single shortest chain of each target exactly like go mod why, or go mod why -m with --module"`
+	Positions      bool     `long:"positions" description:"annotate the imports from first-party packages with the file:line of their import declaration"`
+	BlankImports   bool     `long:"blank-imports" description:"mark the blank (_) and dot (.) imports on the paths"`
+	Versions       bool     `long:"versions" description:"annotate third-party packages with their module version"`
+	PageSize       int      `long:"page-size" description:"print paths N at a time as they are found, waiting for enter between pages on a terminal, implies --stream"`
+	Offset         int      `long:"offset" description:"skip the first N paths found, implies --stream"`
+	Stream         bool     `long:"stream" description:"print paths in text format as they are found, unsorted, instead of collecting them first"`
+	Abbrev         bool     `long:"abbrev" description:"abbreviate the main module path to … in text, tree and markdown output"`
+	Alias          []alias  `long:"alias" description:"abbreviate a module or package path prefix, prefix=alias, in text, tree and markdown output, repeated"`
+	Compress       bool     `long:"compress" description:"fold paths sharing an already printed suffix in text output"`
+	ASCII          bool     `long:"ascii" description:"draw tree output with plain ASCII instead of box-drawing characters"`
+	Color          string   `long:"color" description:"colorize text and tree output" choice:"auto" choice:"always" choice:"never" default:"auto"`
+	FailIfFound    bool     `long:"fail-if-found" description:"exit with status 2 if an import chain to a target exists, to forbid a dependency in CI"`
+	FailIfMissing  bool     `long:"fail-if-missing" description:"exit with status 2 if no import chain to a target exists, to assert a dependency is still used in CI"`
+	Watch          bool     `long:"watch" description:"re-run and reprint the analysis whenever go.mod, go.sum or a Go file changes, until interrupted"`
+	Progress       bool     `long:"progress" description:"report the packages loaded, nodes explored and paths found on stderr"`
+}
+
+// listFlags returns the extra flags passed through to go list.
+func (o Opts) listFlags() []string {
+	var flags []string
+	if o.KeepGoing {
+		flags = append(flags, "-e")
+	}
+	if o.Mod != "" {
+		flags = append(flags, "-mod="+o.Mod)
+	}
+	if o.Tags != "" {
+		flags = append(flags, "-tags", o.Tags)
+	}
+	return flags
+}
+
+// patterns returns the go list patterns of --pattern.
+func (o Opts) patterns() []string {
+	return splitList(o.Pattern)
+}
+
+// defaultPattern reports whether --pattern is the current directory, its default.
+func (o Opts) defaultPattern() bool {
+	patterns := o.patterns()
+	return len(patterns) == 1 && patterns[0] == "."
+}
+
+// workers returns the number of goroutines enumerating paths, or running go list.
+func (o Opts) workers() int {
+	if o.Jobs > 0 {
+		return o.Jobs
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// goEnv returns the environment variables overriding the target platform of go list.
+func (o Opts) goEnv() []string {
+	var env []string
+	if o.GOOS != "" {
+		env = append(env, "GOOS="+o.GOOS)
+	}
+	if o.GOARCH != "" {
+		env = append(env, "GOARCH="+o.GOARCH)
+	}
+	if o.forceCgo {
+		env = append(env, "CGO_ENABLED=1")
+	}
+	return env
+}
+
+func (o Opts) includeTest() bool {
+	return o.IncludeTest || o.TestOnly
+}
+
+func (o Opts) useColor(out *os.File) bool {
+	switch o.Color {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return isTerminal(out)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+var formatExtensions = map[string]string{
+	".txt":      "text",
+	".json":     "json",
+	".dot":      "dot",
+	".gv":       "dot",
+	".svg":      "svg",
+	".mmd":      "mermaid",
+	".puml":     "plantuml",
+	".plantuml": "plantuml",
+	".d2":       "d2",
+	".html":     "html",
+	".htm":      "html",
+	".csv":      "csv",
+	".tsv":      "tsv",
+	".md":       "markdown",
+}
+
+// explicitOption reports whether the option of the long name is given, rather than left to
+// its default.
+func explicitOption(parser *flags.Parser, name string) bool {
+	opt := parser.FindOptionByLongName(name)
+	return opt != nil && opt.IsSet() && !opt.IsSetDefault()
+}
+
+// outputFormat returns the format given on the command line, or the one inferred from the
+// extension of the output file, falling back to the format of the config file, of the
+// environment or the default one. --output, the name of --format in the first versions, is
+// still accepted for it.
+func outputFormat(parser *flags.Parser, opts Opts) string {
+	given := givenOptions(parser.Command, opts.cmdline)
+	switch {
+	case given[parser.FindOptionByLongName("format")]:
+		return opts.Format
+	case given[parser.FindOptionByLongName("output")]:
+		return opts.Output
+	}
+	if format, ok := formatExtensions[strings.ToLower(filepath.Ext(opts.Out))]; ok {
+		return format
+	}
+	if opts.Output != "" {
+		return opts.Output
+	}
+	return opts.Format
+}
+
+// readTargets reads newline-separated targets from a file, or stdin if name is "-". Empty
+// lines and lines starting with # are ignored.
+func readTargets(name string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var targets []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	return targets, scanner.Err()
+}
+
+// resolveTargets returns the packages in the graph matching the target argument. In exact
+// mode the argument itself is returned, unless it is not a package in the graph but a module
+// path, in which case all packages of the module are returned like in module mode.
+func resolveTargets(arg string, match string, packages []Package) ([]string, error) {
+	var re *regexp.Regexp
+	var err error
+	switch match {
+	case "regex":
+		re, err = regexp.Compile(arg)
+	case "glob":
+		re, err = globRegexp(arg)
+	case "module":
+		return modulePackages(arg, packages), nil
+	default:
+		for _, p := range packages {
+			if p.ImportPath == arg {
+				return []string{arg}, nil
+			}
+		}
+		if targets := modulePackages(arg, packages); len(targets) > 0 {
+			return targets, nil
+		}
+		return []string{arg}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid target pattern %q: %v", arg, err)
+	}
+	var targets []string
+	for _, p := range packages {
+		if re.MatchString(p.ImportPath) {
+			targets = append(targets, p.ImportPath)
+		}
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
+
+// modulePackages returns the packages in the graph belonging to module path mod.
+func modulePackages(mod string, packages []Package) []string {
+	var targets []string
+	for _, p := range packages {
+		if p.Module != nil && p.Module.Path == mod {
+			targets = append(targets, p.ImportPath)
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// globRegexp converts a glob to an anchored regexp. `*` and `?` don't match slashes, while
+// `...` matches any string like in go package patterns, and a trailing `/...` also matches
+// the prefix itself.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "/...") && i+4 == len(glob):
+			sb.WriteString("(/.*)?")
+			i += 3
+		case strings.HasPrefix(glob[i:], "..."):
+			sb.WriteString(".*")
+			i += 2
+		case glob[i] == '*':
+			sb.WriteString("[^/]*")
+		case glob[i] == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// analyze finds the import chains from the root to target according to opts.
+func analyze(opts Opts, base Result, target string, forwardMap map[string][]string) Result {
+	res := base
+	res.Target = target
+	isVia := res.viaMatcher(opts.Via)
+	if opts.FailIfFound || opts.FailIfMissing {
+		res.reached = reachable(res.Root, forwardMap, nil)[target]
+	}
+	if opts.Dominators {
+		infof("Analyzing dominators of %s...", target)
+		res.Dominators = dominators(res, forwardMap)
+		return res
+	}
+	if opts.ExplainCut {
+		infof("Analyzing minimal cut of %s...", target)
+		res.Cut = &Cut{Edges: minCut(res, forwardMap)}
+		return res
+	}
+	if opts.WhoImports {
+		res.Importers = directImporters(res, forwardMap)
+		return res
+	}
+	if opts.Weight {
+		infof("Analyzing weight of %s...", target)
+		res.Weight = weight(res, forwardMap)
+		return res
+	}
+	if opts.Reverse {
+		infof("Analyzing dependencies of %s...", target)
+		res.Deps = reverseDeps(res, forwardMap)
+		infof("Successfully analyzed dependencies of %d modules", len(res.Deps))
+		return res
+	}
+	if opts.Subgraph {
+		infof("Analyzing dependency subgraph of %s...", target)
+		if opts.Via == "" {
+			res.Edges = subgraph(res.Root, target, forwardMap, opts.Depth)
+		} else {
+			res.Edges = viaSubgraph(res.Root, target, forwardMap, opts.Depth, isVia)
+		}
+		infof("Successfully analyzed %d dependency edges", len(res.Edges))
+		if opts.Granularity == "module" {
+			res.Edges = collapseEdges(res, res.Edges)
+			res.Root, res.targetNodes = moduleLabel(res, res.Root), []string{moduleLabel(res, target)}
+		}
+		return res
+	}
+	if opts.CountOnly {
+		infof("Counting dependency paths of %s...", target)
+		res.PathCount = countPaths(res.Root, target, forwardMap)
+		return res
+	}
+	done := phase("Analyzing dependency paths of %s", target)
+	if opts.stream != nil {
+		opts.stream.begin(res)
+		res.Truncated = streamPaths(res.Root, target, forwardMap, opts.Depth, opts.MaxPaths, func(p []string) bool {
+			return opts.stream.path(annotatePaths(opts, res, [][]string{p}))
+		})
+		res.TimedOut = isTimedOut()
+		opts.stream.end(res)
+		done()
+		return res
+	}
+	if opts.Depth == 0 && opts.MaxPaths > 0 && (opts.Shortest || opts.MaxPaths <= bestFirstLimit) {
+		res.Paths, res.Truncated = firstPaths(res.Root, target, forwardMap, opts.MaxPaths, opts.Shortest)
+		res.TimedOut = isTimedOut()
+	} else if opts.Shortest {
+		res.Paths = shortestPaths(res.Root, target, forwardMap, opts.Depth)
+	} else {
+		var over bool
+		res.Paths, res.Truncated, over = allPaths(res.Root, target, forwardMap, opts.Depth, opts.MaxPaths, opts.workers(), int64(opts.MaxMemory))
+		if over {
+			return degrade(opts, res, forwardMap)
+		}
+		res.TimedOut = isTimedOut()
+	}
+	done()
+	res = annotatePaths(opts, res, res.Paths)
+	if opts.Via != "" || opts.TestOnly {
+		res.reached = len(res.Paths) > 0
+	}
+	infof("Successfully analyzed %d dependency paths", len(res.Paths))
+	if opts.Summary {
+		res.Summary = summarize(res)
+	} else if opts.GroupBy == "entry" {
+		res.Groups = groupByEntry(res)
+	}
+	if opts.Forks {
+		res.Forks = findForks(res)
+	}
+	if opts.Granularity == "module" {
+		res.Paths = collapsePaths(res, res.Paths)
+		for i := range res.Groups {
+			res.Groups[i].Paths = collapsePaths(res, res.Groups[i].Paths)
+		}
+		res.Root, res.targetNodes = moduleLabel(res, res.Root), []string{moduleLabel(res, target)}
+	}
+	if opts.Rank {
+		res.Rank = rank(res.Paths)
+	}
+	if opts.LicenseSummary {
+		res.LicenseSummary = summarizeLicenses(res)
+	}
+	return res
+}
+
+// viaMatcher returns whether a package is the --via package or belongs to the --via module.
+func (res Result) viaMatcher(via string) func(string) bool {
+	return func(pkg string) bool {
+		p := res.packages[pkg]
+		return pkg == via || (p.Module != nil && p.Module.Path == via)
+	}
+}
+
+// annotatePaths sets the paths of res, filtered by --via and --test-only, truncated by
+// --first-party-only and ordered by --sort, along with the per-path annotations.
+func annotatePaths(opts Opts, res Result, paths [][]string) Result {
+	res.Paths = paths
+	if opts.Via != "" {
+		res.Paths = filterPaths(res.Paths, res.viaMatcher(opts.Via))
+	}
+	if opts.TestOnly {
+		paths := make([][]string, 0, len(res.Paths))
+		for _, p := range res.Paths {
+			if res.isTestOnly(p) {
+				paths = append(paths, p)
+			}
+		}
+		res.Paths = paths
+	}
+	if opts.FirstPartyOnly {
+		res.Paths = firstPartyPaths(res, res.Paths)
+	}
+	if opts.Sort != "" && opts.Sort != "length" {
+		sortPathsBy(res, res.Paths, opts.Sort)
+	}
+	if opts.includeTest() && !opts.ProdOnly && opts.Granularity != "module" {
+		res.TestOnly = make([]bool, len(res.Paths))
+		for i, p := range res.Paths {
+			res.TestOnly[i] = res.isTestOnly(p)
+		}
+	}
+	if opts.Verbose && opts.Granularity != "module" {
+		res.Constraints = pathConstraints(res.packages, res.Paths)
+	}
+	if (opts.Positions || opts.BlankImports) && opts.Granularity != "module" {
+		res.Positions, res.ImportKinds = pathImports(res.packages, res.Paths, opts.Positions, opts.BlankImports)
+	}
+	if opts.IncludeTools && opts.Granularity != "module" {
+		res.ToolOnly = make([]bool, len(res.Paths))
+		for i, p := range res.Paths {
+			res.ToolOnly[i] = res.isToolOnly(p)
+		}
+	}
+	return res
+}
+
+// moduleLabel returns the module hop label of pkg: the module path with its version for
+// dependencies, the bare path for the main module and "std" for the standard library.
+func moduleLabel(res Result, pkg string) string {
+	p := res.packages[pkg]
+	switch {
+	case p.Standard:
+		return "std"
+	case p.Module == nil:
+		return pkg
+	case p.Module.Main || p.Module.Version == "":
+		return p.Module.Path
+	default:
+		return p.Module.Path + "@" + p.Module.Version
+	}
+}
+
+// collapsePaths merges consecutive packages of the same module into a single hop, paths
+// becoming identical are only kept once.
+func collapsePaths(res Result, paths [][]string) [][]string {
+	set := make(map[string]struct{})
+	collapsed := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		var hops []string
+		for _, pkg := range path {
+			label := moduleLabel(res, pkg)
+			if len(hops) == 0 || hops[len(hops)-1] != label {
+				hops = append(hops, label)
+			}
+		}
+		key := strings.Join(hops, "->")
+		if _, ok := set[key]; ok {
+			continue
+		}
+		set[key] = struct{}{}
+		collapsed = append(collapsed, hops)
+	}
+	return collapsed
+}
+
+// firstPartyPaths truncates the paths after their first package out of the main module,
+// paths becoming identical are only kept once.
+func firstPartyPaths(res Result, paths [][]string) [][]string {
+	set := make(map[string]struct{})
+	truncated := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		for j, pkg := range path {
+			if m := res.packages[pkg].Module; m == nil || !m.Main {
+				path = path[:j+1]
+				break
+			}
+		}
+		key := strings.Join(path, "->")
+		if _, ok := set[key]; ok {
+			continue
+		}
+		set[key] = struct{}{}
+		truncated = append(truncated, path)
+	}
+	return truncated
+}
+
+// collapseEdges maps edges to module hops, dropping edges inside a module.
+func collapseEdges(res Result, edges [][2]string) [][2]string {
+	set := make(map[[2]string]struct{})
+	collapsed := make([][2]string, 0, len(edges))
+	for _, e := range edges {
+		c := [2]string{moduleLabel(res, e[0]), moduleLabel(res, e[1])}
+		if c[0] == c[1] {
+			continue
+		}
+		if _, ok := set[c]; ok {
+			continue
+		}
+		set[c] = struct{}{}
+		collapsed = append(collapsed, c)
+	}
+	return collapsed
+}
+
+// graph is the loaded dependency graph.
+type graph struct {
+	packages []Package
+	pkgMap   map[string]Package
+	root     string
+	roots    []string // start packages, one per module in a go.work workspace
+	forward  map[string][]string
+	// toolEdges are the edges which only exist because of tools, with --include-tools.
+	toolEdges map[edge]bool
+}
+
+var (
+	errNoPackage = errors.New("no package found")
+	errNoTarget  = errors.New("no target package found")
+)
+
+// loadGraph runs go list and builds the dependency graph according to opts.
+func loadGraph(opts Opts) (*graph, error) {
+	if opts.TestOnly && opts.ProdOnly {
+		return nil, errors.New("--test-only and --prod-only are mutually exclusive")
+	}
+	l, err := loadPackages(opts)
+	if err != nil {
+		return nil, err
+	}
+	return buildGraph(opts, l)
+}
+
+// loaded is the output of the go command a graph is built from.
+type loaded struct {
+	packages []Package
+	// roots are the last package of every pattern, with several patterns.
+	roots     []string
+	workspace []Module
+	toolEdges map[edge]bool
+}
+
+// listPackages runs go list on the patterns. Several patterns, such as the modules of a
+// workspace, are listed by separate go list commands run concurrently, at most --jobs at a
+// time, whose packages are merged in the order of the patterns, keeping the dependencies of
+// every package before it like go list does. The roots are then the last package listed for
+// every pattern.
+func listPackages(opts Opts, patterns []string) (packages []Package, roots []string, err error) {
+	list := func(patterns []string) ([]Package, error) {
+		if opts.Cache {
+			return cachedGoList(opts, patterns, opts.includeTest(), opts.listFlags(), opts.goEnv())
+		}
+		return runGoList(patterns, opts.includeTest(), opts.listFlags(), opts.goEnv())
+	}
+	if len(patterns) < 2 {
+		packages, err = list(patterns)
+		return packages, nil, err
+	}
+	lists := make([][]Package, len(patterns))
+	errs := make([]error, len(patterns))
+	sem := make(chan struct{}, opts.workers())
+	var wg sync.WaitGroup
+	for i := range patterns {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			debugf("Running go list %s", patterns[i])
+			lists[i], errs[i] = list(patterns[i : i+1])
+		}(i)
+	}
+	wg.Wait()
+	seen := make(map[string]int)
+	for i := range patterns {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		if n := len(lists[i]); n > 0 && !contains(roots, lists[i][n-1].ImportPath) {
+			roots = append(roots, lists[i][n-1].ImportPath) // go list use post-order traversal
+		}
+		for _, p := range lists[i] {
+			j, ok := seen[p.ImportPath]
+			if !ok {
+				seen[p.ImportPath] = len(packages)
+				packages = append(packages, p)
+				continue
+			}
+			// Only the command matching a package lists its test imports.
+			for _, imp := range p.TestImports {
+				if !contains(packages[j].TestImports, imp) {
+					packages[j].TestImports = append(packages[j].TestImports, imp)
+				}
+			}
+		}
+	}
+	return packages, roots, nil
+}
+
+// loadPackages runs the go command to load the packages, or the modules in module mode.
+func loadPackages(opts Opts) (*loaded, error) {
+	if opts.LoadGraph != "" {
+		infof("Loading graph from %s...", opts.LoadGraph)
+		return loadSnapshot(opts.LoadGraph)
+	}
+	var packages []Package
+	var roots []string
+	var toolEdges map[edge]bool
+	var workspace []Module
+	var err error
+	if opts.Mode == "module" {
+		done := phase("Executing go mod graph command to get module requirements")
+		modGraph, err := runGoModGraph()
+		if err != nil {
+			return nil, err
+		}
+		done()
+		mainModules, err := mainModulePaths()
+		if err != nil {
+			return nil, err
+		}
+		packages = moduleGraphPackages(modGraph, mainModules)
+		if len(mainModules) > 1 {
+			roots = mainModules
+		}
+	} else {
+		patterns := opts.patterns()
+		if opts.fromModule != "" {
+			// Start from every package of the module like from a workspace module.
+			workspace = []Module{{Path: opts.fromModule}}
+		} else if workspace, err = workspaceModules(); err != nil {
+			return nil, err
+		} else if len(workspace) > 0 && opts.defaultPattern() {
+			infof("Loading %d workspace modules...", len(workspace))
+			patterns = patterns[:0]
+			for _, m := range workspace {
+				patterns = append(patterns, m.Path+"/...")
+			}
+		}
+		done := phase("Executing go list command to get dependency information")
+		if packages, roots, err = listPackages(opts, patterns); err != nil {
+			return nil, err
+		}
+		done()
+		if opts.IncludeTools {
+			if packages, toolEdges, err = loadTools(opts, patterns, packages); err != nil {
+				return nil, err
+			}
+		}
+		gopath, err := gopathMode()
+		if err != nil {
+			return nil, err
+		}
+		if gopath && len(packages) > 0 {
+			infof("Running in GOPATH mode, guessing modules from repository roots")
+			assignGOPATHModules(packages, packages[len(packages)-1].ImportPath)
+		}
+	}
+	if len(packages) == 0 {
+		return nil, errNoPackage
+	}
+	infof("Successfully got dependency information for %d packages", len(packages))
+	if broken := brokenPackages(packages); len(broken) > 0 {
+		warnf("%d package(s) failed to load, the graph may be incomplete", len(broken))
+		for _, pkg := range broken {
+			infof("  %s", pkg)
+		}
+	}
+	atomic.AddInt64(&progress.packages, int64(len(packages)))
+	l := &loaded{packages: packages, roots: roots, workspace: workspace, toolEdges: toolEdges}
+	if opts.SaveGraph != "" {
+		infof("Saving graph to %s...", opts.SaveGraph)
+		if err := saveSnapshot(opts.SaveGraph, l); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// buildGraph builds the graph of the loaded packages: the roots, and the forward edges
+// without the removed packages and edges. The loaded packages are not modified.
+func buildGraph(opts Opts, l *loaded) (*graph, error) {
+	packages, workspace := l.packages, l.workspace
+	var err error
+	g := &graph{packages: packages, pkgMap: packageMap(packages), toolEdges: l.toolEdges}
+	g.roots = []string{packages[len(packages)-1].ImportPath} // go list use post-order traversal
+	if len(l.roots) > 0 {
+		g.roots = l.roots
+	}
+	if len(workspace) > 0 && (opts.defaultPattern() || opts.fromModule != "") {
+		if roots := workspaceRoots(workspace, packages, g.pkgMap); len(roots) > 0 {
+			g.roots = roots
+		}
+	}
+	if opts.From != "" {
+		if _, ok := g.pkgMap[opts.From]; !ok {
+			return nil, fmt.Errorf("package %s not found in the dependency graph", opts.From)
+		}
+		g.roots = []string{opts.From}
+	}
+	g.root = g.roots[0]
+
+	done := phase("Building dependency graph")
+	g.forward = buildForward(packages, opts.includeTest() && !opts.ProdOnly)
+	if avoid := splitList(append(opts.Avoid, opts.WithoutPkg...)); len(avoid) > 0 {
+		g.forward = removePackages(g.forward, packages, avoid)
+	}
+	if len(opts.Exclude) > 0 {
+		excluded, err := excludedPackages(packages, opts.Exclude)
+		if err != nil {
+			return nil, err
+		}
+		infof("Excluding %d packages matching --exclude", len(excluded))
+		g.forward = removePackages(g.forward, packages, excluded)
+	}
+	if len(opts.WithoutEdge) > 0 {
+		g.forward, err = removeEdges(g.forward, splitList(opts.WithoutEdge))
+		if err != nil {
+			return nil, err
+		}
+	}
+	done()
+	return g, nil
+}
+
+// explain loads the graph and analyzes every target matching the target arguments.
+func explain(opts Opts, targetArgs []string) ([]Result, error) {
+	g, err := loadGraph(opts)
+	if err != nil {
+		return nil, err
+	}
+	return explainGraph(opts, g, targetArgs)
+}
+
+// explainGraph analyzes every target matching the target arguments in the graph.
+func explainGraph(opts Opts, g *graph, targetArgs []string) ([]Result, error) {
+	if err := opts.checkCountOnly(); err != nil {
+		return nil, err
+	}
+	if opts.TailDepth > 0 {
+		if opts.Depth > 0 && opts.Depth != opts.TailDepth {
+			return nil, errors.New("--depth and --tail-depth cannot be given different values")
+		}
+		opts.Depth = opts.TailDepth
+	}
+	var err error
+	packages, forwardMap := g.packages, g.forward
+
+	match := opts.TargetMatch
+	if opts.Module {
+		match = "module"
+	}
+	candidates := packages
+	if opts.IncludeStd == "none" {
+		candidates = nil
+		for _, p := range packages {
+			if !p.Standard {
+				candidates = append(candidates, p)
+			}
+		}
+	}
+	var targets []string
+	seen := make(map[string]bool)
+	for _, targetArg := range targetArgs {
+		matched, err := resolveTargets(targetArg, match, candidates)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) == 0 {
+			warnf("no package matches %s", targetArg)
+		}
+		for _, target := range matched {
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil, errNoTarget
+	}
+	if opts.IncludeStd != "all" {
+		var std []string
+		for _, p := range packages {
+			if p.Standard && (opts.IncludeStd == "none" || !seen[p.ImportPath]) {
+				std = append(std, p.ImportPath)
+			}
+		}
+		forwardMap = removePackages(forwardMap, packages, std)
+	}
+	base := Result{Root: g.root, subgraph: opts.Subgraph, testOnly: testOnlyEdges(packages), toolOnly: g.toolEdges, packages: g.pkgMap}
+	if opts.Licenses || opts.LicenseSummary {
+		infof("Detecting licenses of modules...")
+		base.licenses = moduleLicenses(packages)
+	}
+	if opts.VersionStatus {
+		infof("Checking pseudo-versions and retracted versions of modules...")
+		base.statuses = versionStatuses(packages)
+	}
+	for _, p := range packages {
+		if p.Module != nil && p.Module.Main && p.Module.Dir != "" {
+			if base.vendored, err = vendoredModules(p.Module.Dir); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	results := make([]Result, 0, len(targets))
+	for _, target := range targets {
+		for _, root := range g.roots {
+			base.Root = root
+			results = append(results, analyze(opts, base, target, forwardMap))
+		}
+	}
+	return results, nil
+}
+
+func newPrintOptions(parser *flags.Parser, opts Opts, out *os.File) printOptions {
+	return printOptions{Format: outputFormat(parser, opts), Template: opts.Template, Color: opts.useColor(out), ASCII: opts.ASCII, Compress: opts.Compress, Versions: opts.Versions, Abbrev: opts.Abbrev, Aliases: opts.Alias}
+}
+
+// openOutput returns the file given by --out, or stdout.
+func openOutput(opts Opts) (*os.File, error) {
+	if opts.Out == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(opts.Out)
+}
+
+// writeOutput calls write with the output, closing it afterwards.
+func writeOutput(opts Opts, write func(io.Writer) error) (err error) {
+	out, err := openOutput(opts)
+	if err != nil {
+		return err
+	}
+	defer closeOutput(out, &err)
+	return write(out)
+}
+
+// writeReport writes the report of a command, the only formats of which are text, printed
+// by text, and json, printing res.
+func writeReport(parser *flags.Parser, opts Opts, command string, res Result, text func(io.Writer) error) error {
+	return writeOutput(opts, func(w io.Writer) error {
+		return printReport(w, outputFormat(parser, opts), command, res, func(w io.Writer, _ Result) error {
+			return text(w)
+		})
+	})
+}
+
+// closeOutput closes the file opened by openOutput, leaving stdout open, and sets *err to
+// the error of Close unless it is already set.
+func closeOutput(out *os.File, err *error) {
+	if out == os.Stdout {
+		return
+	}
+	if cerr := out.Close(); *err == nil {
+		*err = cerr
+	}
+}
+
+// withDefaults returns the command line arguments with the name of the command they run,
+// and the options of the environment and of the config file for it. Command line options
+// take precedence over environment variables, themselves taking precedence over the config
+// file.
+func withDefaults(parser *flags.Parser, args []string) ([]string, error) {
+	cmd, head, tail := commandArgs(parser, args)
+	actual := append(head[:len(head):len(head)], tail...)
+	envArgs, err := envArgs(parser, cmd, actual)
+	if err != nil {
+		return nil, err
+	}
+	cfgArgs, err := configArgs(parser, cmd, append(envArgs[:len(envArgs):len(envArgs)], actual...))
+	if err != nil {
+		return nil, err
+	}
+	res := append(append(head[:len(head):len(head)], cfgArgs...), envArgs...)
+	return append(res, tail...), nil
+}
+
+func main() {
+	var opts Opts
+	parser := newParser(&opts)
+	var err error
+	if opts.args, err = withDefaults(parser, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	opts.cmdline = os.Args[1:]
+	args, err := parser.ParseArgs(opts.args)
+	if err == nil && parser.Active == nil {
+		// Targets without a command run the path command.
+		err = parser.CommandHandler(&pathCommand{parser: parser, opts: &opts}, args)
+	}
+	switch {
+	case err == nil:
+		return
+	case err == errFound:
+		exit(exitFound)
+	case flags.WroteHelp(err):
+		fmt.Fprintln(os.Stdout, err.Error())
+	default:
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+	exit(1)
 }
@@ -3,9 +3,530 @@ package main
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/jessevdk/go-flags"
 )
 
 func TestImportMathInTest(t *testing.T) {
 	fmt.Println(sha256.New())
 }
+
+// pathTests are small graphs on which the path searches must agree.
+var pathTests = []struct {
+	name       string
+	forward    map[string][]string
+	start, end string
+	paths      [][]string // every path from start to end, sorted
+	lowerBound bool       // whether counting breaks a cycle
+}{
+	{
+		name:    "diamond",
+		forward: map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}, "d": {"e"}},
+		start:   "a",
+		end:     "e",
+		paths:   [][]string{{"a", "b", "d", "e"}, {"a", "c", "d", "e"}},
+	},
+	{
+		// c is imported by a test of b, which c imports back.
+		name:       "test import cycle",
+		forward:    map[string][]string{"a": {"b"}, "b": {"c", "d"}, "c": {"b", "d"}},
+		start:      "a",
+		end:        "d",
+		paths:      [][]string{{"a", "b", "d"}, {"a", "b", "c", "d"}},
+		lowerBound: true,
+	},
+	{
+		name:    "unreachable",
+		forward: map[string][]string{"a": {"b"}, "c": {"d"}},
+		start:   "a",
+		end:     "d",
+		paths:   [][]string{},
+	},
+}
+
+func TestPathSearchesAgree(t *testing.T) {
+	for _, tt := range pathTests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, workers := range []int{1, 4} {
+				paths, truncated, overBudget := allPaths(tt.start, tt.end, tt.forward, 0, 0, workers, 0)
+				if !reflect.DeepEqual(paths, tt.paths) || truncated || overBudget {
+					t.Errorf("allPaths with %d workers = %v, %v, %v, want %v", workers, paths, truncated, overBudget, tt.paths)
+				}
+			}
+
+			streamed := [][]string{}
+			more := streamPaths(tt.start, tt.end, tt.forward, 0, 0, func(path []string) bool {
+				streamed = append(streamed, path)
+				return true
+			})
+			sortPaths(streamed)
+			if !reflect.DeepEqual(streamed, tt.paths) || more {
+				t.Errorf("streamPaths = %v, %v, want %v", streamed, more, tt.paths)
+			}
+
+			first, truncated := firstPaths(tt.start, tt.end, tt.forward, 0, false)
+			sortPaths(first)
+			if !reflect.DeepEqual(first, tt.paths) || truncated {
+				t.Errorf("firstPaths = %v, %v, want %v", first, truncated, tt.paths)
+			}
+
+			count := countPaths(tt.start, tt.end, tt.forward)
+			if want := strconv.Itoa(len(tt.paths)); count.Paths != want || count.LowerBound != tt.lowerBound {
+				t.Errorf("countPaths = %+v, want %s paths, lower bound %v", count, want, tt.lowerBound)
+			}
+
+			var shortest [][]string
+			for _, path := range tt.paths {
+				if len(path) == len(tt.paths[0]) {
+					shortest = append(shortest, path)
+				}
+			}
+			if shortest == nil {
+				shortest = [][]string{}
+			}
+			if got := shortestPaths(tt.start, tt.end, tt.forward, 0); !reflect.DeepEqual(got, shortest) {
+				t.Errorf("shortestPaths = %v, want %v", got, shortest)
+			}
+			if got, _ := firstPaths(tt.start, tt.end, tt.forward, 0, true); !reflect.DeepEqual(got, shortest) {
+				t.Errorf("firstPaths of the shortest paths = %v, want %v", got, shortest)
+			}
+		})
+	}
+}
+
+func TestPathSearchesDepth(t *testing.T) {
+	forward := map[string][]string{"a": {"b", "c"}, "b": {"c"}, "c": {"d"}}
+	want := [][]string{{"a", "c", "d"}, {"b", "c", "d"}}
+	for _, workers := range []int{1, 4} {
+		paths, truncated, _ := allPaths("a", "d", forward, 2, 0, workers, 0)
+		if !reflect.DeepEqual(paths, want) || truncated {
+			t.Errorf("allPaths with %d workers = %v, %v, want %v", workers, paths, truncated, want)
+		}
+	}
+	var streamed [][]string
+	streamPaths("a", "d", forward, 2, 0, func(path []string) bool {
+		streamed = append(streamed, path)
+		return true
+	})
+	sortPaths(streamed)
+	if !reflect.DeepEqual(streamed, want) {
+		t.Errorf("streamPaths = %v, want %v", streamed, want)
+	}
+}
+
+func TestPathSearchesLimit(t *testing.T) {
+	tt := pathTests[0]
+	isPath := func(path []string) bool {
+		for _, p := range tt.paths {
+			if reflect.DeepEqual(path, p) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, workers := range []int{1, 4} {
+		paths, truncated, _ := allPaths(tt.start, tt.end, tt.forward, 0, 1, workers, 0)
+		if len(paths) != 1 || !isPath(paths[0]) || !truncated {
+			t.Errorf("allPaths with %d workers = %v, %v, want one path, truncated", workers, paths, truncated)
+		}
+	}
+	var streamed [][]string
+	more := streamPaths(tt.start, tt.end, tt.forward, 0, 1, func(path []string) bool {
+		streamed = append(streamed, path)
+		return true
+	})
+	if len(streamed) != 1 || !isPath(streamed[0]) || !more {
+		t.Errorf("streamPaths = %v, %v, want one path, more", streamed, more)
+	}
+	first, truncated := firstPaths(tt.start, tt.end, tt.forward, 1, false)
+	if len(first) != 1 || !isPath(first[0]) || !truncated {
+		t.Errorf("firstPaths = %v, %v, want one path, truncated", first, truncated)
+	}
+}
+
+// testResult returns the result from the root to the target over the packages, each in the
+// module named by the path up to its first slash, the one of the root being the main module.
+func testResult(root, target string, forward map[string][]string) Result {
+	res := Result{Root: root, Target: target, packages: make(map[string]Package)}
+	mainModule := strings.SplitN(root, "/", 2)[0]
+	add := func(pkg string) {
+		mod := strings.SplitN(pkg, "/", 2)[0]
+		res.packages[pkg] = Package{ImportPath: pkg, Module: &Module{Path: mod, Main: mod == mainModule}}
+	}
+	for from, imports := range forward {
+		add(from)
+		for _, to := range imports {
+			add(to)
+		}
+	}
+	return res
+}
+
+// cutTests are small graphs of first-party packages in m and third-party ones elsewhere.
+var cutTests = []struct {
+	name       string
+	forward    map[string][]string
+	dominators Dominators
+	cut        [][2]string
+}{
+	{
+		name:       "diamond",
+		forward:    map[string][]string{"m": {"m/a", "m/b"}, "m/a": {"y/d"}, "m/b": {"y/d"}, "y/d": {"z/t"}},
+		dominators: Dominators{Packages: []string{"y/d"}, Modules: []string{"y"}},
+		cut:        [][2]string{{"m", "m/a"}, {"m", "m/b"}},
+	},
+	{
+		name:       "articulation point",
+		forward:    map[string][]string{"m": {"m/a"}, "m/a": {"x/p", "x/q"}, "x/p": {"z/t"}, "x/q": {"z/t"}},
+		dominators: Dominators{Packages: []string{"m/a"}, Modules: []string{"x"}},
+		cut:        [][2]string{{"m", "m/a"}},
+	},
+	{
+		name:       "disjoint paths",
+		forward:    map[string][]string{"m": {"m/a", "y/q"}, "m/a": {"x/p"}, "x/p": {"z/t"}, "y/q": {"z/t"}},
+		dominators: Dominators{Packages: []string{}, Modules: []string{}},
+		cut:        [][2]string{{"m", "m/a"}, {"m", "y/q"}},
+	},
+}
+
+func TestDominatorsAndCut(t *testing.T) {
+	for _, tt := range cutTests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := testResult("m", "z/t", tt.forward)
+			if got := dominators(res, tt.forward); !reflect.DeepEqual(*got, tt.dominators) {
+				t.Errorf("dominators = %+v, want %+v", *got, tt.dominators)
+			}
+			cut := minCut(res, tt.forward)
+			sort.Slice(cut, func(i, j int) bool { return cut[i][0]+" "+cut[i][1] < cut[j][0]+" "+cut[j][1] })
+			if !reflect.DeepEqual(cut, tt.cut) {
+				t.Errorf("minCut = %v, want %v", cut, tt.cut)
+			}
+		})
+	}
+
+	// Without a first-party edge to cut, there is no cut.
+	forward := map[string][]string{"x/p": {"z/t"}}
+	res := testResult("x/p", "z/t", forward)
+	res.packages["x/p"].Module.Main = false
+	if cut := minCut(res, forward); cut != nil {
+		t.Errorf("minCut without a first-party edge = %v, want nil", cut)
+	}
+}
+
+func TestMergeTestPackages(t *testing.T) {
+	tests := []struct {
+		name     string
+		packages []Package // in the post-order of go list -deps -test
+		want     []Package
+	}{
+		{
+			name: "internal and external tests",
+			packages: []Package{
+				{ImportPath: "b", Name: "b"},
+				{ImportPath: "a", Name: "a", Imports: []string{"b"}},
+				{ImportPath: "c", Name: "c"},
+				{ImportPath: "d", Name: "d"},
+				{ImportPath: "a [a.test]", Name: "a", ForTest: "a", Imports: []string{"b", "c"}},
+				{ImportPath: "a_test [a.test]", Name: "a_test", ForTest: "a", Imports: []string{"a [a.test]", "d"}},
+				{ImportPath: "a.test", Name: "main", Imports: []string{"a [a.test]", "a_test [a.test]"}},
+			},
+			want: []Package{
+				{ImportPath: "b", Name: "b"},
+				{ImportPath: "c", Name: "c"},
+				{ImportPath: "d", Name: "d"},
+				{ImportPath: "a", Name: "a", Imports: []string{"b"}, TestImports: []string{"c", "d"}},
+			},
+		},
+		{
+			// e imports a, so it is recompiled against the test variant of a.
+			name: "dependency recompiled for a test",
+			packages: []Package{
+				{ImportPath: "a", Name: "a"},
+				{ImportPath: "e", Name: "e", Imports: []string{"a"}},
+				{ImportPath: "a [a.test]", Name: "a", ForTest: "a"},
+				{ImportPath: "e [a.test]", Name: "e", ForTest: "a", Imports: []string{"a [a.test]"}},
+				{ImportPath: "a_test [a.test]", Name: "a_test", ForTest: "a", Imports: []string{"a [a.test]", "e [a.test]"}},
+				{ImportPath: "a.test", Name: "main", Imports: []string{"a [a.test]", "a_test [a.test]"}},
+			},
+			want: []Package{
+				{ImportPath: "e", Name: "e", Imports: []string{"a"}},
+				{ImportPath: "a", Name: "a", TestImports: []string{"e"}},
+			},
+		},
+		{
+			name: "test imports of a package not matched",
+			packages: []Package{
+				{ImportPath: "b", Name: "b", TestImports: []string{"c"}},
+				{ImportPath: "a", Name: "a", Imports: []string{"b"}},
+			},
+			want: []Package{
+				{ImportPath: "b", Name: "b"},
+				{ImportPath: "a", Name: "a", Imports: []string{"b"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeTestPackages(tt.packages); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeTestPackages() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeight(t *testing.T) {
+	mainModule := &Module{Path: "m", Main: true}
+	x := &Module{Path: "x", Version: "v1.0.0"}
+	y := &Module{Path: "y", Version: "v1.2.0"}
+	v := &Module{Path: "v", Version: "v0.1.0"}
+	packages := []Package{
+		{ImportPath: "fmt", Standard: true},
+		{ImportPath: "v/v", Module: v, Imports: []string{"fmt"}},
+		{ImportPath: "y/u", Module: y},
+		{ImportPath: "y/t", Module: y, Imports: []string{"y/u", "v/v"}},
+		{ImportPath: "x/p", Module: x, Imports: []string{"y/u"}},
+		{ImportPath: "m/a", Module: mainModule, Imports: []string{"y/t", "x/p"}},
+		{ImportPath: "m/b", Module: mainModule},
+		{ImportPath: "m", Module: mainModule, Imports: []string{"m/a", "m/b", "fmt"}},
+		{ImportPath: "z/z", Module: &Module{Path: "z", Version: "v1.0.0"}},
+	}
+	forward := buildForward(packages, false)
+	res := Result{Root: "m", packages: make(map[string]Package)}
+	for _, p := range packages {
+		res.packages[p.ImportPath] = p
+	}
+	tests := []struct {
+		target string
+		want   Weight
+	}{
+		// y/u stays through x/p, so y stays, and fmt is imported by the root.
+		{target: "y/t", want: Weight{Packages: []string{"v/v", "y/t"}, Modules: []string{"v@v0.1.0"}}},
+		// The main module stays through the root and m/b.
+		{target: "m/a", want: Weight{Packages: []string{"m/a", "v/v", "x/p", "y/t", "y/u"}, Modules: []string{"v@v0.1.0", "x@v1.0.0", "y@v1.2.0"}}},
+		{target: "y/u", want: Weight{Packages: []string{"y/u"}, Modules: []string{}}},
+		{target: "z/z", want: Weight{Packages: []string{}, Modules: []string{}}},
+	}
+	for _, tt := range tests {
+		res.Target = tt.target
+		if got := weight(res, forward); !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("weight(%s) = %+v, want %+v", tt.target, *got, tt.want)
+		}
+	}
+}
+
+func TestCheckCountOnly(t *testing.T) {
+	tests := []struct {
+		opts Opts
+		err  string
+	}{
+		{opts: Opts{PathOpts: PathOpts{CountOnly: true}}},
+		{opts: Opts{PathOpts: PathOpts{Depth: 2, Via: "a"}}},
+		{opts: Opts{PathOpts: PathOpts{CountOnly: true, Depth: 2}}, err: "--count-only cannot be used with --depth"},
+		{opts: Opts{PathOpts: PathOpts{CountOnly: true, TailDepth: 2}}, err: "--count-only cannot be used with --tail-depth"},
+		{opts: Opts{PathOpts: PathOpts{CountOnly: true, Via: "a"}}, err: "--count-only cannot be used with --via"},
+		{opts: Opts{PathOpts: PathOpts{CountOnly: true, TestOnly: true}}, err: "--count-only cannot be used with --test-only"},
+		{opts: Opts{PathOpts: PathOpts{CountOnly: true, FirstPartyOnly: true}}, err: "--count-only cannot be used with --first-party-only"},
+		{opts: Opts{PathOpts: PathOpts{CountOnly: true, TestOnly: true, Depth: 1}}, err: "--count-only cannot be used with --depth, --test-only"},
+	}
+	for _, tt := range tests {
+		err := tt.opts.checkCountOnly()
+		if (err == nil && tt.err != "") || (err != nil && err.Error() != tt.err) {
+			t.Errorf("checkCountOnly() = %v, want %q", err, tt.err)
+		}
+	}
+	want := "--stream cannot be used with --count-only"
+	if err := (Opts{PathOpts: PathOpts{CountOnly: true, Sort: "length", Granularity: "package", GroupBy: "none"}}).checkStream("text"); err == nil || err.Error() != want {
+		t.Errorf("checkStream() = %v, want %q", err, want)
+	}
+}
+
+func TestDiffPaths(t *testing.T) {
+	before := []Result{
+		{Target: "t", Root: "r", Paths: [][]string{{"r", "a", "t"}, {"r", "b", "t"}}},
+		{Target: "old", Root: "r", Paths: [][]string{{"r", "old"}}},
+	}
+	after := []Result{
+		{Target: "t", Root: "r", Paths: [][]string{{"r", "b", "t"}, {"r", "c", "t"}}},
+		{Target: "new", Root: "r", Paths: [][]string{{"r", "new"}}},
+	}
+	want := []PathDiff{
+		{Target: "t", Root: "r", Added: [][]string{{"r", "c", "t"}}, Removed: [][]string{{"r", "a", "t"}}},
+		{Target: "old", Root: "r", Added: [][]string{}, Removed: [][]string{{"r", "old"}}},
+		{Target: "new", Root: "r", Added: [][]string{{"r", "new"}}, Removed: [][]string{}},
+	}
+	if got := diffPaths(before, after); !reflect.DeepEqual(got, want) {
+		t.Errorf("diffPaths = %v, want %v", got, want)
+	}
+}
+
+// parseCommandLine parses the arguments like main does, without running the command, and
+// returns the options, with those of the path command when it runs.
+func parseCommandLine(t *testing.T, args []string) (*flags.Parser, Opts) {
+	var opts Opts
+	parser := newParser(&opts)
+	parser.CommandHandler = func(cmd flags.Commander, _ []string) error {
+		if c, ok := cmd.(*pathCommand); ok {
+			opts.PathOpts = c.PathOpts
+		}
+		return nil
+	}
+	var err error
+	if opts.args, err = withDefaults(parser, args); err != nil {
+		t.Fatal(err)
+	}
+	opts.cmdline = args
+	if _, err := parser.ParseArgs(opts.args); err != nil {
+		t.Fatal(err)
+	}
+	return parser, opts
+}
+
+func TestCommandArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		cmd        string
+		head, tail []string
+	}{
+		{args: []string{}, cmd: "gomodwhy", head: nil, tail: []string{}},
+		{args: []string{"--help"}, cmd: "gomodwhy", head: nil, tail: []string{"--help"}},
+		{args: []string{"-p", "cycles"}, cmd: "gomodwhy", head: nil, tail: []string{"-p", "cycles"}},
+		{args: []string{"fmt"}, cmd: "path", head: []string{"path"}, tail: []string{"fmt"}},
+		{args: []string{"-d", "2", "--"}, cmd: "path", head: []string{"path"}, tail: []string{"-d", "2", "--"}},
+		{args: []string{"-tp", "./...", "path"}, cmd: "path", head: []string{"-tp", "./...", "path"}, tail: []string{}},
+		{args: []string{"--", "path"}, cmd: "path", head: []string{"path"}, tail: []string{"--", "path"}},
+		{args: []string{"-p./...", "cycles", "-t"}, cmd: "cycles", head: []string{"-p./...", "cycles"}, tail: []string{"-t"}},
+	}
+	for _, tt := range tests {
+		var opts Opts
+		parser := newParser(&opts)
+		cmd, head, tail := commandArgs(parser, tt.args)
+		if cmd.Name != tt.cmd || !reflect.DeepEqual(head, tt.head) || !reflect.DeepEqual(tail, tt.tail) {
+			t.Errorf("commandArgs(%v) = %s, %v, %v, want %s, %v, %v", tt.args, cmd.Name, head, tail, tt.cmd, tt.head, tt.tail)
+		}
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	config := filepath.Join(t.TempDir(), ".gomodwhy.yaml")
+	if err := os.WriteFile(config, []byte("pattern: ./sub\ndepth: 3\nexclude: [a]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args    []string
+		pattern []string
+		depth   int
+	}{
+		{args: []string{"x"}, pattern: []string{"./sub"}, depth: 3},
+		{args: []string{"-p", ".", "x"}, pattern: []string{"."}, depth: 3},
+		{args: []string{"--pattern=.", "-p", "./...", "x"}, pattern: []string{".", "./..."}, depth: 3},
+		{args: []string{"-d1", "x"}, pattern: []string{"./sub"}, depth: 1},
+		{args: []string{"x", "--depth", "1", "-p."}, pattern: []string{"."}, depth: 1},
+		{args: []string{"cycles", "-p."}, pattern: []string{"."}, depth: 0},
+	}
+	for _, tt := range tests {
+		_, opts := parseCommandLine(t, append([]string{"--config", config}, tt.args...))
+		if !reflect.DeepEqual(opts.Pattern, tt.pattern) || opts.Depth != tt.depth || !reflect.DeepEqual(opts.Exclude, []string{"a"}) {
+			t.Errorf("%v: pattern %v, depth %d, exclude %v, want %v, %d, [a]", tt.args, opts.Pattern, opts.Depth, opts.Exclude, tt.pattern, tt.depth)
+		}
+	}
+}
+
+func TestEnvPrecedence(t *testing.T) {
+	t.Setenv("GOMODWHY_CONFIG", "")
+	t.Setenv("GOMODWHY_PATTERN", "./sub")
+	t.Setenv("GOMODWHY_FORMAT", "json")
+	tests := []struct {
+		args    []string
+		pattern []string
+		format  string
+	}{
+		{args: []string{"x"}, pattern: []string{"./sub"}, format: "json"},
+		{args: []string{"-p", ".", "x"}, pattern: []string{"."}, format: "json"},
+		{args: []string{"--out", "r.dot", "x"}, pattern: []string{"./sub"}, format: "dot"},
+		{args: []string{"--out", "r.dot", "-f", "text", "x"}, pattern: []string{"./sub"}, format: "text"},
+		{args: []string{"-o", "tsv", "x"}, pattern: []string{"./sub"}, format: "tsv"},
+		{args: []string{"--out", "r.dot", "--output=csv", "x"}, pattern: []string{"./sub"}, format: "csv"},
+	}
+	for _, tt := range tests {
+		parser, opts := parseCommandLine(t, tt.args)
+		if format := outputFormat(parser, opts); !reflect.DeepEqual(opts.Pattern, tt.pattern) || format != tt.format {
+			t.Errorf("%v: pattern %v, format %s, want %v, %s", tt.args, opts.Pattern, format, tt.pattern, tt.format)
+		}
+	}
+}
+
+// writeWorkspace writes a go.work workspace of the modules a and b, which both require the
+// module c through a replacement by a local directory, and runs the go commands in it.
+func writeWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.work":  "go 1.18\n\nuse (\n\t./a\n\t./b\n)\n",
+		"a/go.mod": "module example.com/a\n\ngo 1.18\n\nrequire example.com/c v1.0.0\n\nreplace example.com/c => ../c\n",
+		"a/a.go":   "package a\n\nimport _ \"example.com/c\"\n",
+		"b/go.mod": "module example.com/b\n\ngo 1.18\n\nrequire example.com/c v1.0.0\n\nreplace example.com/c => ../c\n",
+		"b/b.go":   "package b\n\nimport _ \"example.com/c\"\n",
+		"c/go.mod": "module example.com/c\n\ngo 1.18\n",
+		"c/c.go":   "package c\n",
+	}
+	for name, content := range files {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOWORK", "")
+	goDir = dir
+	t.Cleanup(func() { goDir = "" })
+}
+
+func TestWorkspaceModuleGraph(t *testing.T) {
+	writeWorkspace(t)
+	mainModules, err := mainModulePaths()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com/a", "example.com/b"}; !reflect.DeepEqual(mainModules, want) {
+		t.Fatalf("mainModulePaths() = %v, want %v", mainModules, want)
+	}
+	modGraph, err := runGoModGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := explainVersion("example.com/c", "v1.0.0", modGraph, mainModules)
+	wantReport := &VersionReport{
+		Module:   "example.com/c",
+		Selected: "v1.0.0",
+		Requirements: []Requirer{
+			{Requirer: "example.com/a", Version: "v1.0.0", Selects: true},
+			{Requirer: "example.com/b", Version: "v1.0.0", Selects: true},
+		},
+		Path: []string{"example.com/a", "example.com/c@v1.0.0"},
+	}
+	if !reflect.DeepEqual(report, wantReport) {
+		t.Errorf("explainVersion() = %+v, want %+v", report, wantReport)
+	}
+
+	g, err := loadGraph(Opts{Pattern: []string{"."}, Mode: "module"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g.roots, mainModules) {
+		t.Errorf("roots = %v, want %v", g.roots, mainModules)
+	}
+	wantInventory := []ModuleChain{{Module: "example.com/c", Version: "v1.0.0", Path: []string{"example.com/a", "example.com/c@v1.0.0"}}}
+	if modules := inventory(g); !reflect.DeepEqual(modules, wantInventory) {
+		t.Errorf("inventory() = %+v, want %+v", modules, wantInventory)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// MajorVersions lists the major versions of a module present in the build.
+type MajorVersions struct {
+	Module   string        `json:"module"`
+	Versions []ModuleChain `json:"versions"`
+}
+
+// ModuleChain is a module with the shortest import chain from the root to one of its packages.
+type ModuleChain struct {
+	Module  string   `json:"module"`
+	Version string   `json:"version,omitempty"`
+	Path    []string `json:"path"`
+}
+
+func (c ModuleChain) label() string {
+	if c.Version == "" {
+		return c.Module
+	}
+	return c.Module + "@" + c.Version
+}
+
+type majorsCommand struct {
+	parser *flags.Parser
+	opts   *Opts
+}
+
+var majorSuffix = regexp.MustCompile(`^(.+?)(?:/v[2-9]|/v[1-9][0-9]+|\.v[0-9]+)$`)
+
+// majorBase returns the module path without its major version suffix, e.g. foo for foo/v2
+// and gopkg.in/yaml for gopkg.in/yaml.v3.
+func majorBase(module string) string {
+	if m := majorSuffix.FindStringSubmatch(module); m != nil {
+		return m[1]
+	}
+	return module
+}
+
+// shortestChains returns a shortest import chain from any of the starts to every reachable
+// package.
+func shortestChains(starts []string, forward map[string][]string) map[string][]string {
+	parent := make(map[string]string)
+	var queue []string
+	for _, start := range starts {
+		if _, ok := parent[start]; !ok {
+			parent[start] = ""
+			queue = append(queue, start)
+		}
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for _, next := range forward[node] {
+			if _, ok := parent[next]; !ok {
+				parent[next] = node
+				queue = append(queue, next)
+			}
+		}
+	}
+	chains := make(map[string][]string, len(parent))
+	for pkg := range parent {
+		var chain []string
+		for n := pkg; n != ""; n = parent[n] {
+			chain = append(chain, n)
+		}
+		chains[pkg] = reversePaths([][]string{chain})[0]
+	}
+	return chains
+}
+
+// moduleChains returns the shortest import chain from the root to every module of the
+// build, keyed by module path.
+func moduleChains(g *graph) map[string]ModuleChain {
+	res := make(map[string]ModuleChain)
+	for pkg, chain := range shortestChains(g.roots, g.forward) {
+		m := g.pkgMap[pkg].Module
+		if m == nil {
+			continue
+		}
+		if c, ok := res[m.Path]; !ok || len(chain) < len(c.Path) || (len(chain) == len(c.Path) && pkg < c.Path[len(c.Path)-1]) {
+			res[m.Path] = ModuleChain{Module: m.Path, Version: m.Version, Path: chain}
+		}
+	}
+	return res
+}
+
+// majorVersions returns the modules present in the build with more than one major version.
+func majorVersions(g *graph) []MajorVersions {
+	groups := make(map[string][]ModuleChain)
+	for _, c := range moduleChains(g) {
+		base := majorBase(c.Module)
+		groups[base] = append(groups[base], c)
+	}
+	res := make([]MajorVersions, 0)
+	for base, chains := range groups {
+		if len(chains) < 2 {
+			continue
+		}
+		sort.Slice(chains, func(i, j int) bool { return chains[i].Module < chains[j].Module })
+		res = append(res, MajorVersions{Module: base, Versions: chains})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Module < res[j].Module })
+	return res
+}
+
+func (c *majorsCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	g, err := loadGraph(*c.opts)
+	if err != nil {
+		return err
+	}
+	majors := majorVersions(g)
+
+	return writeReport(c.parser, *c.opts, "majors", Result{Root: g.root, Majors: majors}, func(w io.Writer) error {
+		return printMajors(w, majors)
+	})
+}
+
+func printMajors(w io.Writer, majors []MajorVersions) error {
+	fmt.Fprintln(w, "# majors")
+	if len(majors) == 0 {
+		fmt.Fprintln(w, "no module with multiple major versions found")
+		return nil
+	}
+	for _, m := range majors {
+		fmt.Fprintf(w, "%s (%d major versions):\n", m.Module, len(m.Versions))
+		for _, v := range m.Versions {
+			fmt.Fprintf(w, "  %s\n", v.label())
+			for _, pkg := range v.Path {
+				fmt.Fprintf(w, "    %s\n", pkg)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
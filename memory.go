@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// byteSize is a size in bytes given with an optional unit, e.g. 512MB or 4GiB.
+type byteSize int64
+
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// UnmarshalFlag implements flags.Unmarshaler.
+func (s *byteSize) UnmarshalFlag(value string) error {
+	v := strings.ToUpper(strings.TrimSpace(value))
+	factor := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, factor = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.factor
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q, expected e.g. 512MB or 4GB", value)
+	}
+	*s = byteSize(n * float64(factor))
+	return nil
+}
+
+// degrade replaces the paths of res, which outgrew --max-memory, by the subgraph of the
+// edges on any path, whose size is bounded by the graph instead of the number of paths.
+func degrade(opts Opts, res Result, forwardMap map[string][]string) Result {
+	warnf("the import chains of %s exceed --max-memory, printing their subgraph instead", res.Target)
+	res.Paths, res.Truncated, res.Degraded = nil, false, true
+	res.subgraph = true
+	if opts.Via == "" {
+		res.Edges = subgraph(res.Root, res.Target, forwardMap, opts.Depth)
+	} else {
+		res.Edges = viaSubgraph(res.Root, res.Target, forwardMap, opts.Depth, res.viaMatcher(opts.Via))
+	}
+	if opts.Granularity == "module" {
+		res.Edges = collapseEdges(res, res.Edges)
+		res.Root, res.targetNodes = moduleLabel(res, res.Root), []string{moduleLabel(res, res.Target)}
+	}
+	return res
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// runGoModGraph returns the module requirement graph, nodes are path@version except for the
+// main module. The go and toolchain pseudo-modules are left out.
+func runGoModGraph() (map[string][]string, error) {
+	cmd := goCommand("mod", "graph")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go mod graph failed: %v\n\n%s\n%s", err, cmd.String(), stderr.String())
+	}
+	graph := make(map[string][]string)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.HasPrefix(fields[1], "go@") || strings.HasPrefix(fields[1], "toolchain@") {
+			continue
+		}
+		graph[fields[0]] = append(graph[fields[0]], fields[1])
+	}
+	return graph, scanner.Err()
+}
+
+func splitVersion(node string) (path, version string) {
+	if i := strings.LastIndex(node, "@"); i >= 0 {
+		return node[:i], node[i+1:]
+	}
+	return node, ""
+}
+
+// mainModulePaths returns the paths of the main modules: the main module, or every module
+// of the go.work workspace.
+func mainModulePaths() ([]string, error) {
+	modules, err := listModules()
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, m := range modules {
+		if m.Main {
+			paths = append(paths, m.Path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("no main module found")
+	}
+	return paths, nil
+}
+
+// requirementChain returns a shortest requirement chain from one of the main modules to the
+// node of the module graph, or nil if none requires it.
+func requirementChain(mainModules []string, node string, modGraph map[string][]string) []string {
+	var chain []string
+	for _, m := range mainModules {
+		if paths := shortestPaths(m, node, modGraph, 0); len(paths) > 0 && (chain == nil || len(paths[0]) < len(chain)) {
+			chain = paths[0]
+		}
+	}
+	return chain
+}
+
+// chainRoot returns the main module a requirement chain starts from, the first main module
+// without chain.
+func chainRoot(chain []string, mainModules []string) string {
+	if len(chain) > 0 {
+		return chain[0]
+	}
+	return mainModules[0]
+}
+
+// moduleGraphPackages turns the module requirement graph into packages, one per module
+// version, importing the module versions they require. Like go list, the main modules come
+// last.
+func moduleGraphPackages(modGraph map[string][]string, mainModules []string) []Package {
+	nodes := make(map[string]bool)
+	for from, tos := range modGraph {
+		nodes[from] = true
+		for _, to := range tos {
+			nodes[to] = true
+		}
+	}
+	names := make([]string, 0, len(nodes))
+	for node := range nodes {
+		if !contains(mainModules, node) {
+			names = append(names, node)
+		}
+	}
+	sort.Strings(names)
+	names = append(names, mainModules...)
+
+	packages := make([]Package, 0, len(names))
+	for _, node := range names {
+		path, version := splitVersion(node)
+		packages = append(packages, Package{
+			ImportPath: node,
+			Module:     &Module{Path: path, Version: version, Main: contains(mainModules, node)},
+			Imports:    modGraph[node],
+		})
+	}
+	return packages
+}
+
+// listModules runs go list -m -json with the given arguments, the main modules by default.
+func listModules(args ...string) ([]Module, error) {
+	cmd := goCommand(append([]string{"list", "-m", "-json"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list failed: %v\n\n%s\n%s", err, cmd.String(), stderr.String())
+	}
+	var modules []Module
+	dec := json.NewDecoder(bytes.NewReader(output))
+	for {
+		var m Module
+		if err := dec.Decode(&m); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("go list failed: %v\n\n%s", err, cmd.String())
+		}
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
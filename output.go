@@ -0,0 +1,1220 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	texttemplate "text/template"
+)
+
+type Result struct {
+	Target         string            `json:"target"`
+	Root           string            `json:"root"`
+	Paths          [][]string        `json:"paths,omitempty"`
+	Edges          [][2]string       `json:"edges,omitempty"`
+	Summary        *Summary          `json:"summary,omitempty"`
+	Deps           []ModuleDeps      `json:"deps,omitempty"`
+	Importers      *Importers        `json:"importers,omitempty"`
+	Dominators     *Dominators       `json:"dominators,omitempty"`
+	Cut            *Cut              `json:"cut,omitempty"`
+	Groups         []PathGroup       `json:"groups,omitempty"`
+	Rank           *Rank             `json:"rank,omitempty"`
+	Version        *VersionReport    `json:"version,omitempty"`
+	Sum            *SumReport        `json:"sum,omitempty"`
+	Majors         []MajorVersions   `json:"majors,omitempty"`
+	Search         *Search           `json:"search,omitempty"`
+	Inventory      []ModuleChain     `json:"inventory,omitempty"`
+	Licenses       map[string]string `json:"licenses,omitempty"`
+	VersionStatus  map[string]string `json:"version_status,omitempty"`
+	LicenseSummary []LicenseGroup    `json:"license_summary,omitempty"`
+	Weight         *Weight           `json:"weight,omitempty"`
+	Platforms      [][]string        `json:"platforms,omitempty"`
+	TestOnly       []bool            `json:"test_only,omitempty"`
+	ToolOnly       []bool            `json:"tool_only,omitempty"`
+	Constraints    [][]string        `json:"constraints,omitempty"`
+	Positions      [][]string        `json:"positions,omitempty"`
+	ImportKinds    [][]string        `json:"import_kinds,omitempty"`
+	Versions       map[string]string `json:"versions,omitempty"`
+	Replaced       map[string]string `json:"replaced,omitempty"`
+	Errors         map[string]string `json:"errors,omitempty"`
+	Vendored       map[string]bool   `json:"vendored,omitempty"`
+	Binary         *BinaryModule     `json:"binary,omitempty"`
+	PlatformOnly   []PlatformPackage `json:"platform_only,omitempty"`
+	PathCount      *PathCount        `json:"path_count,omitempty"`
+	Forks          []Fork            `json:"forks,omitempty"`
+	Scan           []ModuleScan      `json:"scan,omitempty"`
+	Cgo            []CgoPackage      `json:"cgo,omitempty"`
+	GoVersion      *GoVersionReport  `json:"go_version,omitempty"`
+	Diff           []PathDiff        `json:"diff,omitempty"`
+	Cycles         [][]string        `json:"cycles,omitempty"`
+	Unused         []Requirement     `json:"unused,omitempty"`
+	// Truncated reports that more paths exist than listed because of --max-paths.
+	Truncated bool `json:"truncated,omitempty"`
+	// TimedOut reports that the path search was stopped by --timeout.
+	TimedOut bool `json:"timed_out,omitempty"`
+	// Degraded reports that the paths outgrew --max-memory and their subgraph is given instead.
+	Degraded bool `json:"degraded,omitempty"`
+
+	subgraph bool
+	// reached reports that the root reaches the target, through --via with it, computed for
+	// --fail-if-found and --fail-if-missing.
+	reached bool
+	// targetNodes are the graph nodes of the targets if they differ from Target, for
+	// merged results or module granularity.
+	targetNodes []string
+	testOnly    map[edge]bool
+	toolOnly    map[edge]bool
+	packages    map[string]Package
+	licenses    map[string]string
+	statuses    map[string]string // version status by module path
+	vendored    map[string]bool
+}
+
+type printOptions struct {
+	Format   string
+	Template string
+	Color    bool
+	ASCII    bool
+	Compress bool
+	Versions bool
+	Abbrev   bool
+	Aliases  []alias
+}
+
+// printResults prints the results of all targets. Graph formats render a single merged
+// graph named after the target argument, the other formats print the results one by one.
+func printResults(w io.Writer, opts printOptions, targetArg string, results []Result) error {
+	if len(results) == 1 {
+		return printPaths(w, opts, results[0])
+	}
+	switch opts.Format {
+	case "dot", "svg", "mermaid", "plantuml", "d2", "html":
+		return printPaths(w, opts, mergeResults(targetArg, results))
+	case "json":
+		return printJSONResults(w, opts, results)
+	case "csv", "tsv":
+		if !results[0].hasReport() {
+			comma := ','
+			if opts.Format == "tsv" {
+				comma = '\t'
+			}
+			return printTargetEdgeList(w, results, comma)
+		}
+	}
+	for _, res := range results {
+		if err := printPaths(w, opts, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printJSONResults prints the results of several targets as one JSON array.
+func printJSONResults(w io.Writer, opts printOptions, results []Result) error {
+	list := make([]json.RawMessage, 0, len(results))
+	for _, res := range results {
+		var buf bytes.Buffer
+		if err := printPaths(&buf, opts, res); err != nil {
+			return err
+		}
+		list = append(list, buf.Bytes())
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
+
+func mergeResults(target string, results []Result) Result {
+	merged := results[0]
+	merged.Target = target
+	merged.targetNodes = nil
+	for _, res := range results {
+		merged.targetNodes = append(merged.targetNodes, res.targets()...)
+	}
+	merged.Paths, merged.Edges, merged.Truncated, merged.TimedOut = nil, nil, false, false
+	seen := make(map[[2]string]bool)
+	for _, res := range results {
+		merged.Paths = append(merged.Paths, res.Paths...)
+		merged.Truncated = merged.Truncated || res.Truncated
+		merged.TimedOut = merged.TimedOut || res.TimedOut
+		for _, e := range res.Edges {
+			if !seen[e] {
+				seen[e] = true
+				merged.Edges = append(merged.Edges, e)
+			}
+		}
+	}
+	return merged
+}
+
+func printPaths(w io.Writer, opts printOptions, res Result) error {
+	switch {
+	case res.Deps != nil:
+		return printReport(w, opts.Format, "--reverse", res, printDeps)
+	case res.Weight != nil:
+		return printReport(w, opts.Format, "--weight", res, printWeight)
+	case res.Dominators != nil:
+		return printReport(w, opts.Format, "--dominators", res, printDominators)
+	case res.Cut != nil:
+		return printReport(w, opts.Format, "--explain-cut", res, printCut)
+	case res.Importers != nil:
+		return printReport(w, opts.Format, "--who-imports", res, printImporters)
+	case res.Summary != nil:
+		res.Paths = nil
+		return printReport(w, opts.Format, "--summary", res, printSummary)
+	case res.LicenseSummary != nil:
+		res.Paths = nil
+		return printReport(w, opts.Format, "--license-summary", res, printLicenseSummary)
+	case res.PathCount != nil:
+		return printReport(w, opts.Format, "--count-only", res, printPathCount)
+	case res.Forks != nil:
+		res.Paths = nil
+		return printReport(w, opts.Format, "--forks", res, printForks)
+	case res.Rank != nil:
+		res.Paths = nil
+		return printReport(w, opts.Format, "--rank", res, printRank)
+	case res.Groups != nil:
+		res.Paths = nil
+		return printReport(w, opts.Format, "--group-by", res, printGroups)
+	}
+	if res.subgraph {
+		switch opts.Format {
+		case "tree", "table", "markdown":
+			return fmt.Errorf("format %s is not supported with --subgraph", opts.Format)
+		}
+	}
+	switch opts.Format {
+	case "json":
+		if opts.Versions {
+			res.Versions = res.versions()
+		}
+		if replaced := res.replaced(); len(replaced) > 0 {
+			res.Replaced = replaced
+		}
+		if res.licenses != nil {
+			res.Licenses = res.pathLicenses()
+		}
+		if res.statuses != nil {
+			res.VersionStatus = res.pathVersionStatuses()
+		}
+		if broken := res.pathErrors(); len(broken) > 0 {
+			res.Errors = broken
+		}
+		if res.vendored != nil {
+			res.Vendored = res.pathVendored()
+		}
+		return printJSON(w, res)
+	case "dot":
+		return printDOT(w, res)
+	case "svg":
+		return printSVG(w, res)
+	case "mermaid":
+		return printMermaid(w, res)
+	case "plantuml":
+		return printPlantUML(w, res)
+	case "d2":
+		return printD2(w, res)
+	case "tree":
+		return printTree(w, res, res.painter(opts), treeBranches(opts.ASCII))
+	case "html":
+		return printHTML(w, res)
+	case "csv":
+		return printEdgeList(w, res, ',')
+	case "tsv":
+		return printEdgeList(w, res, '\t')
+	case "table":
+		return printTable(w, res)
+	case "markdown":
+		return printMarkdown(w, res, res.painter(printOptions{Versions: opts.Versions, Abbrev: opts.Abbrev, Aliases: opts.Aliases}))
+	case "template":
+		return printTemplate(w, opts.Template, res)
+	default:
+		if opts.Compress {
+			return printCompressed(w, res, res.painter(opts))
+		}
+		return printText(w, res, res.painter(opts))
+	}
+}
+
+// isTestOnly reports whether the path has an edge which only exists in test imports.
+func (res Result) isTestOnly(path []string) bool {
+	for i := 1; i < len(path); i++ {
+		if res.testOnly[edge{from: path[i-1], to: path[i]}] {
+			return true
+		}
+	}
+	return false
+}
+
+// isToolOnly reports whether the path has an edge which only exists because of tools.
+func (res Result) isToolOnly(path []string) bool {
+	for i := 1; i < len(path); i++ {
+		if res.toolOnly[edge{from: path[i-1], to: path[i]}] {
+			return true
+		}
+	}
+	return false
+}
+
+// hasReport reports whether the result is a report of a mode other than path listing.
+func (res Result) hasReport() bool {
+	return res.Deps != nil || res.Weight != nil || res.Dominators != nil || res.Cut != nil || res.Importers != nil || res.Summary != nil || res.Groups != nil || res.Cycles != nil || res.Unused != nil || res.Rank != nil || res.Version != nil || res.Sum != nil || res.Majors != nil || res.Search != nil || res.Inventory != nil || res.LicenseSummary != nil || res.Forks != nil || res.PathCount != nil || res.Cgo != nil || res.GoVersion != nil || res.Diff != nil
+}
+
+// printReport prints the report of a mode which only supports text and json formats.
+func printReport(w io.Writer, format string, mode string, res Result, text func(io.Writer, Result) error) error {
+	switch format {
+	case "text":
+		return text(w, res)
+	case "json":
+		return printJSON(w, res)
+	default:
+		return fmt.Errorf("format %s is not supported with %s", format, mode)
+	}
+}
+
+// targets returns the graph nodes of the targets.
+func (res Result) targets() []string {
+	if len(res.targetNodes) > 0 {
+		return res.targetNodes
+	}
+	return []string{res.Target}
+}
+
+func (res Result) isTarget(pkg string) bool {
+	for _, t := range res.targets() {
+		if t == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// moduleOf returns the module path providing pkg, "std" for standard library packages
+// and an empty string if unknown.
+func (res Result) moduleOf(pkg string) string {
+	p := res.packages[pkg]
+	switch {
+	case p.Standard:
+		return "std"
+	case p.Module != nil:
+		return p.Module.Path
+	default:
+		return ""
+	}
+}
+
+const (
+	colorReset = "\x1b[0m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorDim   = "\x1b[2m"
+)
+
+// painter returns a function decorating package names with ANSI colors: the target
+// in red, packages of the main module in green and standard library packages dimmed.
+// With versions, third-party packages are suffixed with their module version, and path
+// prefixes are abbreviated with --abbrev and --alias.
+func (res Result) painter(opts printOptions) func(string) string {
+	abbrev := res.abbreviator(opts)
+	return func(pkg string) string {
+		label := abbrev(pkg)
+		if v := res.versionOf(pkg); opts.Versions && v != "" && !strings.HasSuffix(pkg, "@"+v) {
+			label += "@" + v
+		}
+		if r := res.replacementOf(pkg); r != "" {
+			label += " => " + r
+		}
+		if lic := res.licenseOf(pkg); lic != "" {
+			label += " [" + lic + "]"
+		}
+		if s := res.versionStatusOf(pkg); s != "" {
+			label += " (" + s + ")"
+		}
+		if res.packages[pkg].Error != nil {
+			label += " (broken)"
+		}
+		if vendored, ok := res.vendoredOf(pkg); ok && !vendored {
+			label += " (not vendored)"
+		} else if ok {
+			label += " (vendored)"
+		}
+		if !opts.Color {
+			return label
+		}
+		p := res.packages[pkg]
+		switch {
+		case res.isTarget(pkg):
+			return colorRed + label + colorReset
+		case p.Module != nil && p.Module.Main:
+			return colorGreen + label + colorReset
+		case p.Standard:
+			return colorDim + label + colorReset
+		default:
+			return label
+		}
+	}
+}
+
+// versionOf returns the module version of a third-party package, or "" otherwise.
+func (res Result) versionOf(pkg string) string {
+	p := res.packages[pkg]
+	if p.Module == nil || p.Module.Main {
+		return ""
+	}
+	return p.Module.Version
+}
+
+// replacementOf returns the replacement of the module of pkg, as written in a go.mod
+// replace directive, or "" if it is not replaced.
+func (res Result) replacementOf(pkg string) string {
+	p := res.packages[pkg]
+	if p.Module == nil || p.Module.Replace == nil {
+		return ""
+	}
+	if p.Module.Replace.Version == "" {
+		return p.Module.Replace.Path
+	}
+	return p.Module.Replace.Path + " " + p.Module.Replace.Version
+}
+
+// pathErrors returns the load error of every broken package on the paths.
+func (res Result) pathErrors() map[string]string {
+	errs := make(map[string]string)
+	for _, p := range res.Paths {
+		for _, pkg := range p {
+			if e := res.packages[pkg].Error; e != nil {
+				errs[pkg] = e.Err
+			}
+		}
+	}
+	return errs
+}
+
+// replaced returns the replacement of every package on the paths whose module is replaced.
+func (res Result) replaced() map[string]string {
+	replaced := make(map[string]string)
+	for _, p := range res.Paths {
+		for _, pkg := range p {
+			if r := res.replacementOf(pkg); r != "" {
+				replaced[pkg] = r
+			}
+		}
+	}
+	return replaced
+}
+
+// versions returns the module path and version of every third-party package on the paths.
+func (res Result) versions() map[string]string {
+	versions := make(map[string]string)
+	for _, p := range res.Paths {
+		for _, pkg := range p {
+			if v := res.versionOf(pkg); v != "" {
+				versions[pkg] = res.packages[pkg].Module.Path + "@" + v
+			}
+		}
+	}
+	return versions
+}
+
+func printText(w io.Writer, res Result, paint func(string) string) error {
+	fmt.Fprintf(w, "# %s\n", paint(res.Target))
+	if res.subgraph {
+		if len(res.Edges) == 0 {
+			fmt.Fprintln(w, "no import chain found")
+		}
+		for _, e := range res.Edges {
+			fmt.Fprintf(w, "%s -> %s\n", paint(e[0]), paint(e[1]))
+		}
+		return nil
+	}
+	if len(res.Paths) == 0 {
+		fmt.Fprintln(w, "no import chain found")
+		return nil
+	}
+	for i := range res.Paths {
+		printPath(w, res, i, paint)
+	}
+	printTruncated(w, res)
+	return nil
+}
+
+// printPath prints the i-th path of res with its annotations, followed by a blank line.
+func printPath(w io.Writer, res Result, i int, paint func(string) string) {
+	p := res.Paths[i]
+	if res.Platforms != nil {
+		fmt.Fprintf(w, "(%s)\n", strings.Join(res.Platforms[i], ", "))
+	}
+	if res.TestOnly != nil && res.TestOnly[i] {
+		fmt.Fprintln(w, "(test only)")
+	}
+	if res.ToolOnly != nil && res.ToolOnly[i] {
+		fmt.Fprintln(w, "(tool only)")
+	}
+	for j, item := range p {
+		label := paint(item)
+		if res.Constraints != nil && res.Constraints[i][j] != "" {
+			label += " [" + res.Constraints[i][j] + "]"
+		}
+		if res.Positions != nil && res.Positions[i][j] != "" {
+			label += " (" + res.Positions[i][j] + ")"
+		}
+		if res.ImportKinds != nil && res.ImportKinds[i][j] != "" {
+			label += " (" + res.ImportKinds[i][j] + " import)"
+		}
+		if j > 0 && res.testOnly[edge{from: p[j-1], to: item}] {
+			fmt.Fprintf(w, "%s (test import)\n", label)
+		} else if j > 0 && res.toolOnly[edge{from: p[j-1], to: item}] {
+			fmt.Fprintf(w, "%s (tool import)\n", label)
+		} else {
+			fmt.Fprintln(w, label)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+func printTruncated(w io.Writer, res Result) {
+	if res.TimedOut {
+		fmt.Fprintf(w, "… truncated by timeout, only the %d import chains found so far are shown\n", len(res.Paths))
+	} else if res.Truncated {
+		fmt.Fprintf(w, "… more import chains exist, only the first %d are shown\n", len(res.Paths))
+	}
+}
+
+// printCompressed prints the paths like printText, but a path whose suffix from some
+// intermediate package X was already printed is folded into an "and N more paths reach X"
+// note below the first path printed with that suffix.
+func printCompressed(w io.Writer, res Result, paint func(string) string) error {
+	if res.subgraph || len(res.Paths) == 0 {
+		return printText(w, res, paint)
+	}
+
+	type hub struct {
+		pkg   string
+		count int
+	}
+	printed := make(map[string]int) // suffix key -> index of the printed path
+	var order []int
+	hubs := make(map[int][]*hub)
+	for i, p := range res.Paths {
+		folded := false
+		for k := 1; k < len(p)-1; k++ {
+			idx, ok := printed[strings.Join(p[k:], "->")]
+			if !ok {
+				continue
+			}
+			var h *hub
+			for _, c := range hubs[idx] {
+				if c.pkg == p[k] {
+					h = c
+				}
+			}
+			if h == nil {
+				h = &hub{pkg: p[k]}
+				hubs[idx] = append(hubs[idx], h)
+			}
+			h.count++
+			folded = true
+			break
+		}
+		if folded {
+			continue
+		}
+		order = append(order, i)
+		for k := 1; k < len(p)-1; k++ {
+			key := strings.Join(p[k:], "->")
+			if _, ok := printed[key]; !ok {
+				printed[key] = i
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "# %s\n", paint(res.Target))
+	for _, i := range order {
+		for _, item := range res.Paths[i] {
+			fmt.Fprintln(w, paint(item))
+		}
+		for _, h := range hubs[i] {
+			fmt.Fprintf(w, "… and %d more path(s) reach %s\n", h.count, paint(h.pkg))
+		}
+		fmt.Fprintln(w)
+	}
+	printTruncated(w, res)
+	return nil
+}
+
+// entryPackage returns the last package of the main module on the path, i.e. where the
+// chain leaves first-party code, or an empty string if there is none.
+func (res Result) entryPackage(path []string) string {
+	for j := len(path) - 1; j >= 0; j-- {
+		if m := res.packages[path[j]].Module; m != nil && m.Main {
+			return path[j]
+		}
+	}
+	return ""
+}
+
+type PathGroup struct {
+	Entry string     `json:"entry"`
+	Count int        `json:"count"`
+	Paths [][]string `json:"paths"`
+}
+
+// groupByEntry groups the paths by their first-party entry package, largest groups first.
+func groupByEntry(res Result) []PathGroup {
+	index := make(map[string]int)
+	groups := make([]PathGroup, 0)
+	for _, p := range res.Paths {
+		entry := res.entryPackage(p)
+		i, ok := index[entry]
+		if !ok {
+			i = len(groups)
+			index[entry] = i
+			groups = append(groups, PathGroup{Entry: entry})
+		}
+		groups[i].Count++
+		groups[i].Paths = append(groups[i].Paths, p)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Entry < groups[j].Entry
+	})
+	return groups
+}
+
+func printGroups(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	if len(res.Groups) == 0 {
+		fmt.Fprintln(w, "no import chain found")
+		return nil
+	}
+	for _, g := range res.Groups {
+		entry := g.Entry
+		if entry == "" {
+			entry = "(no first-party package)"
+		}
+		fmt.Fprintf(w, "## %s (%d path(s))\n", entry, g.Count)
+		for _, p := range g.Paths {
+			for _, item := range p {
+				fmt.Fprintln(w, item)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	printTruncated(w, res)
+	return nil
+}
+
+type Summary struct {
+	Paths           int      `json:"paths"`
+	DirectImporters []string `json:"direct_importers"`
+	ShortestHops    int      `json:"shortest_hops"`
+	LongestHops     int      `json:"longest_hops"`
+	EntryPackages   []string `json:"entry_packages"`
+}
+
+// summarize aggregates the paths, entry packages are the last packages of the main module
+// on each path, i.e. where the chain leaves first-party code.
+func summarize(res Result) *Summary {
+	sum := &Summary{Paths: len(res.Paths), DirectImporters: []string{}, EntryPackages: []string{}}
+	importers := make(map[string]struct{})
+	entries := make(map[string]struct{})
+	for i, p := range res.Paths {
+		hops := len(p) - 1
+		if i == 0 || hops < sum.ShortestHops {
+			sum.ShortestHops = hops
+		}
+		if hops > sum.LongestHops {
+			sum.LongestHops = hops
+		}
+		if len(p) >= 2 {
+			importers[p[len(p)-2]] = struct{}{}
+		}
+		if entry := res.entryPackage(p); entry != "" {
+			entries[entry] = struct{}{}
+		}
+	}
+	for k := range importers {
+		sum.DirectImporters = append(sum.DirectImporters, k)
+	}
+	for k := range entries {
+		sum.EntryPackages = append(sum.EntryPackages, k)
+	}
+	sort.Strings(sum.DirectImporters)
+	sort.Strings(sum.EntryPackages)
+	return sum
+}
+
+func printSummary(w io.Writer, res Result) error {
+	sum := res.Summary
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	fmt.Fprintf(w, "paths: %d\n", sum.Paths)
+	if sum.Paths == 0 {
+		return nil
+	}
+	fmt.Fprintf(w, "shortest path: %d hop(s)\n", sum.ShortestHops)
+	fmt.Fprintf(w, "longest path: %d hop(s)\n", sum.LongestHops)
+	fmt.Fprintf(w, "direct importers (%d):\n", len(sum.DirectImporters))
+	for _, pkg := range sum.DirectImporters {
+		fmt.Fprintf(w, "  %s\n", pkg)
+	}
+	fmt.Fprintf(w, "first-party entry packages (%d):\n", len(sum.EntryPackages))
+	for _, pkg := range sum.EntryPackages {
+		fmt.Fprintf(w, "  %s\n", pkg)
+	}
+	return nil
+}
+
+// Rank lists the intermediate packages by the number of paths passing through them.
+type Rank struct {
+	Paths    int             `json:"paths"`
+	Packages []RankedPackage `json:"packages"`
+}
+
+type RankedPackage struct {
+	Package string `json:"package"`
+	Paths   int    `json:"paths"`
+}
+
+// rank counts the paths passing through every package between the root and the target,
+// the most used ones first.
+func rank(paths [][]string) *Rank {
+	counts := make(map[string]int)
+	for _, p := range paths {
+		for i := 1; i < len(p)-1; i++ {
+			counts[p[i]]++
+		}
+	}
+	r := &Rank{Paths: len(paths), Packages: make([]RankedPackage, 0, len(counts))}
+	for pkg, n := range counts {
+		r.Packages = append(r.Packages, RankedPackage{Package: pkg, Paths: n})
+	}
+	sort.Slice(r.Packages, func(i, j int) bool {
+		if r.Packages[i].Paths != r.Packages[j].Paths {
+			return r.Packages[i].Paths > r.Packages[j].Paths
+		}
+		return r.Packages[i].Package < r.Packages[j].Package
+	})
+	return r
+}
+
+func printRank(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	r := res.Rank
+	if len(r.Packages) == 0 {
+		fmt.Fprintln(w, "no intermediate package found")
+		return nil
+	}
+	fmt.Fprintf(w, "paths through each package, out of %d:\n", r.Paths)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, p := range r.Packages {
+		fmt.Fprintf(tw, "  %d\t%d%%\t%s\n", p.Paths, p.Paths*100/r.Paths, p.Package)
+	}
+	return tw.Flush()
+}
+
+type ModuleDeps struct {
+	Module   string       `json:"module"`
+	Version  string       `json:"version,omitempty"`
+	Packages []PackageDep `json:"packages"`
+}
+
+type PackageDep struct {
+	ImportPath string `json:"import_path"`
+	// Exclusive reports that the package is only in the build because of the target.
+	Exclusive bool `json:"exclusive"`
+}
+
+func printDeps(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	if len(res.Deps) == 0 {
+		fmt.Fprintln(w, "no dependency found")
+		return nil
+	}
+	total, exclusive := 0, 0
+	for _, m := range res.Deps {
+		name := m.Module
+		if m.Version != "" {
+			name += " " + m.Version
+		}
+		fmt.Fprintf(w, "%s (%d package(s))\n", name, len(m.Packages))
+		for _, p := range m.Packages {
+			total++
+			if p.Exclusive {
+				exclusive++
+				fmt.Fprintf(w, "  %s (exclusive)\n", p.ImportPath)
+			} else {
+				fmt.Fprintf(w, "  %s\n", p.ImportPath)
+			}
+		}
+	}
+	fmt.Fprintf(w, "\n%d package(s) in %d module(s), %d only imported because of %s\n", total, len(res.Deps), exclusive, res.Target)
+	return nil
+}
+
+type Importers struct {
+	FirstParty []string `json:"first_party"`
+	ThirdParty []string `json:"third_party"`
+	Standard   []string `json:"standard"`
+}
+
+func printImporters(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	imp := res.Importers
+	if len(imp.FirstParty)+len(imp.ThirdParty)+len(imp.Standard) == 0 {
+		fmt.Fprintln(w, "no importer found")
+		return nil
+	}
+	for _, group := range []struct {
+		name string
+		pkgs []string
+	}{
+		{"first-party", imp.FirstParty},
+		{"third-party", imp.ThirdParty},
+		{"standard library", imp.Standard},
+	} {
+		if len(group.pkgs) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d):\n", group.name, len(group.pkgs))
+		for _, pkg := range group.pkgs {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+	}
+	return nil
+}
+
+// Weight lists what the target uniquely contributes to the build, the target included.
+type Weight struct {
+	Packages []string `json:"packages"`
+	Modules  []string `json:"modules"`
+}
+
+func printWeight(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	weight := res.Weight
+	fmt.Fprintf(w, "removing it drops %d package(s) and %d module(s) from the build\n", len(weight.Packages), len(weight.Modules))
+	if len(weight.Modules) > 0 {
+		fmt.Fprintln(w, "modules:")
+		for _, mod := range weight.Modules {
+			fmt.Fprintf(w, "  %s\n", mod)
+		}
+	}
+	if len(weight.Packages) > 0 {
+		fmt.Fprintln(w, "packages:")
+		for _, pkg := range weight.Packages {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+	}
+	return nil
+}
+
+type Dominators struct {
+	Packages []string `json:"packages"`
+	Modules  []string `json:"modules"`
+}
+
+func printDominators(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	dom := res.Dominators
+	if len(dom.Packages) == 0 && len(dom.Modules) == 0 {
+		fmt.Fprintln(w, "no single package or module gates the target")
+		return nil
+	}
+	if len(dom.Packages) > 0 {
+		fmt.Fprintln(w, "every import chain passes through package(s):")
+		for _, pkg := range dom.Packages {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+	}
+	if len(dom.Modules) > 0 {
+		fmt.Fprintln(w, "every import chain passes through module(s):")
+		for _, mod := range dom.Modules {
+			fmt.Fprintf(w, "  %s\n", mod)
+		}
+	}
+	return nil
+}
+
+type Cut struct {
+	// Edges are first-party imports, removing all of them disconnects the root from the target.
+	Edges [][2]string `json:"edges"`
+}
+
+func printCut(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	if len(res.Cut.Edges) == 0 {
+		fmt.Fprintln(w, "no first-party import edges can disconnect the target")
+		return nil
+	}
+	fmt.Fprintf(w, "remove %d first-party import(s) to drop the target:\n", len(res.Cut.Edges))
+	owner := ""
+	for _, e := range res.Cut.Edges {
+		if e[0] != owner {
+			owner = e[0]
+			fmt.Fprintf(w, "  %s\n", owner)
+		}
+		fmt.Fprintf(w, "    imports %s\n", e[1])
+	}
+	return nil
+}
+
+func printJSON(w io.Writer, res Result) error {
+	if res.Paths == nil && !res.subgraph && !res.hasReport() {
+		res.Paths = [][]string{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(res)
+}
+
+func printTable(w io.Writer, res Result) error {
+	fmt.Fprintf(w, "# %s\n", res.Target)
+	if len(res.Paths) == 0 {
+		fmt.Fprintln(w, "no import chain found")
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tMODULE\tVERSION\tTEST ONLY")
+	for i, p := range res.Paths {
+		if i > 0 {
+			fmt.Fprintln(tw, "\t\t\t")
+		}
+		for j, item := range p {
+			mod, version, testOnly := res.moduleOf(item), "", false
+			if m := res.packages[item].Module; m != nil {
+				version = m.Version
+			}
+			if j > 0 {
+				testOnly = res.testOnly[edge{from: p[j-1], to: item}]
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%v\n", item, orDash(mod), orDash(version), testOnly)
+		}
+	}
+	return tw.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func printMarkdown(w io.Writer, res Result, paint func(string) string) error {
+	fmt.Fprintln(w, "<details>")
+	fmt.Fprintf(w, "<summary>%d import chain(s) for <code>%s</code></summary>\n\n", len(res.Paths), template.HTMLEscapeString(res.Target))
+	if len(res.Paths) == 0 {
+		fmt.Fprintln(w, "no import chain found")
+	}
+	for _, p := range res.Paths {
+		fmt.Fprintln(w, "```")
+		for _, item := range p {
+			fmt.Fprintln(w, paint(item))
+		}
+		fmt.Fprintln(w, "```")
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w, "</details>")
+	return nil
+}
+
+type edge struct {
+	from string
+	to   string
+}
+
+// edges returns the subgraph edges in subgraph mode, otherwise the edges of all paths.
+func (res Result) edges() []edge {
+	if !res.subgraph {
+		return pathEdges(res.Paths)
+	}
+	edges := make([]edge, 0, len(res.Edges))
+	for _, e := range res.Edges {
+		edges = append(edges, edge{from: e[0], to: e[1]})
+	}
+	return edges
+}
+
+// pathEdges returns the deduplicated edges of all paths in order of first appearance.
+func pathEdges(paths [][]string) []edge {
+	set := make(map[edge]struct{})
+	var edges []edge
+	for _, path := range paths {
+		for i := 0; i+1 < len(path); i++ {
+			e := edge{from: path[i], to: path[i+1]}
+			if _, ok := set[e]; ok {
+				continue
+			}
+			set[e] = struct{}{}
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+func printDOT(w io.Writer, res Result) error {
+	fmt.Fprintln(w, "digraph gomodwhy {")
+	fmt.Fprintln(w, "\trankdir=LR;")
+	fmt.Fprintln(w, "\tnode [shape=box];")
+	fmt.Fprintf(w, "\t%q [style=bold];\n", res.Root)
+	for _, t := range res.targets() {
+		fmt.Fprintf(w, "\t%q [style=filled, fillcolor=lightgrey];\n", t)
+	}
+	for _, e := range res.edges() {
+		fmt.Fprintf(w, "\t%q -> %q;\n", e.from, e.to)
+	}
+	fmt.Fprintln(w, "}")
+	return nil
+}
+
+func printMermaid(w io.Writer, res Result) error {
+	ids := make(map[string]string)
+	id := func(pkg string) string {
+		if _, ok := ids[pkg]; !ok {
+			ids[pkg] = fmt.Sprintf("n%d", len(ids))
+			fmt.Fprintf(w, "    %s[\"%s\"]\n", ids[pkg], pkg)
+		}
+		return ids[pkg]
+	}
+	fmt.Fprintln(w, "graph TD")
+	id(res.Root)
+	for _, t := range res.targets() {
+		id(t)
+	}
+	for _, e := range res.edges() {
+		from, to := id(e.from), id(e.to)
+		fmt.Fprintf(w, "    %s --> %s\n", from, to)
+	}
+	return nil
+}
+
+func printPlantUML(w io.Writer, res Result) error {
+	ids := make(map[string]string)
+	id := func(pkg string) string {
+		if _, ok := ids[pkg]; !ok {
+			ids[pkg] = fmt.Sprintf("n%d", len(ids))
+			color := ""
+			if pkg == res.Root {
+				color = " #lightgreen"
+			} else if res.isTarget(pkg) {
+				color = " #pink"
+			}
+			fmt.Fprintf(w, "component \"%s\" as %s%s\n", pkg, ids[pkg], color)
+		}
+		return ids[pkg]
+	}
+	fmt.Fprintln(w, "@startuml")
+	fmt.Fprintln(w, "left to right direction")
+	id(res.Root)
+	for _, t := range res.targets() {
+		id(t)
+	}
+	for _, e := range res.edges() {
+		from, to := id(e.from), id(e.to)
+		fmt.Fprintf(w, "%s --> %s\n", from, to)
+	}
+	fmt.Fprintln(w, "@enduml")
+	return nil
+}
+
+// printD2 renders the paths in D2 syntax, grouping packages into containers by module.
+func printD2(w io.Writer, res Result) error {
+	containers := make(map[string]string)
+	ids := make(map[string]string)
+	id := func(pkg string) string {
+		if _, ok := ids[pkg]; ok {
+			return ids[pkg]
+		}
+		nid := fmt.Sprintf("n%d", len(ids))
+		if mod := res.moduleOf(pkg); mod != "" {
+			if _, ok := containers[mod]; !ok {
+				containers[mod] = fmt.Sprintf("m%d", len(containers))
+				fmt.Fprintf(w, "%s: %q\n", containers[mod], mod)
+			}
+			nid = containers[mod] + "." + nid
+		}
+		ids[pkg] = nid
+		fmt.Fprintf(w, "%s: %q\n", nid, pkg)
+		if pkg == res.Root {
+			fmt.Fprintf(w, "%s.style.fill: \"#dafbe1\"\n", nid)
+		} else if res.isTarget(pkg) {
+			fmt.Fprintf(w, "%s.style.fill: \"#ffebe9\"\n", nid)
+		}
+		return nid
+	}
+	fmt.Fprintln(w, "direction: right")
+	id(res.Root)
+	for _, t := range res.targets() {
+		id(t)
+	}
+	for _, e := range res.edges() {
+		from, to := id(e.from), id(e.to)
+		fmt.Fprintf(w, "%s -> %s\n", from, to)
+	}
+	return nil
+}
+
+type treeNode struct {
+	name     string
+	children []*treeNode
+}
+
+func (n *treeNode) child(name string) *treeNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &treeNode{name: name}
+	n.children = append(n.children, c)
+	return c
+}
+
+// buildTree merges paths into a prefix tree, the returned node is a virtual root.
+func buildTree(paths [][]string) *treeNode {
+	root := new(treeNode)
+	for _, path := range paths {
+		n := root
+		for _, item := range path {
+			n = n.child(item)
+		}
+	}
+	return root
+}
+
+// treeBranches returns the branch and indent strings for middle and last children.
+func treeBranches(ascii bool) [4]string {
+	if ascii {
+		return [4]string{"|-- ", "|   ", "`-- ", "    "}
+	}
+	return [4]string{"├── ", "│   ", "└── ", "    "}
+}
+
+func printTree(w io.Writer, res Result, paint func(string) string, branches [4]string) error {
+	fmt.Fprintf(w, "# %s\n", paint(res.Target))
+	if len(res.Paths) == 0 {
+		fmt.Fprintln(w, "no import chain found")
+		return nil
+	}
+	for _, top := range buildTree(res.Paths).children {
+		fmt.Fprintln(w, paint(top.name))
+		printTreeChildren(w, top, "", paint, branches)
+	}
+	printTruncated(w, res)
+	return nil
+}
+
+func printTreeChildren(w io.Writer, n *treeNode, prefix string, paint func(string) string, branches [4]string) {
+	for i, c := range n.children {
+		branch, indent := branches[0], branches[1]
+		if i == len(n.children)-1 {
+			branch, indent = branches[2], branches[3]
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, paint(c.name))
+		printTreeChildren(w, c, prefix+indent, paint, branches)
+	}
+}
+
+//go:embed report.html
+var htmlReport string
+
+var htmlTemplate = template.Must(template.New("report").Parse(htmlReport))
+
+type htmlNode struct {
+	Name     string
+	Children []htmlNode
+}
+
+func toHTMLNodes(nodes []*treeNode) []htmlNode {
+	res := make([]htmlNode, 0, len(nodes))
+	for _, n := range nodes {
+		res = append(res, htmlNode{Name: n.name, Children: toHTMLNodes(n.children)})
+	}
+	return res
+}
+
+type htmlGraph struct {
+	Root    string   `json:"root"`
+	Targets []string `json:"targets"`
+	Nodes   []string `json:"nodes"`
+	Edges   [][2]int `json:"edges"`
+}
+
+func printHTML(w io.Writer, res Result) error {
+	graph := htmlGraph{Root: res.Root, Targets: res.targets(), Nodes: []string{}, Edges: [][2]int{}}
+	index := make(map[string]int)
+	id := func(pkg string) int {
+		if _, ok := index[pkg]; !ok {
+			index[pkg] = len(graph.Nodes)
+			graph.Nodes = append(graph.Nodes, pkg)
+		}
+		return index[pkg]
+	}
+	for _, e := range res.edges() {
+		graph.Edges = append(graph.Edges, [2]int{id(e.from), id(e.to)})
+	}
+	return htmlTemplate.Execute(w, struct {
+		Result
+		Tree  []htmlNode
+		Graph htmlGraph
+	}{res, toHTMLNodes(buildTree(res.Paths).children), graph})
+}
+
+func printEdgeList(w io.Writer, res Result, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write([]string{"from", "to", "test_only"}); err != nil {
+		return err
+	}
+	for _, e := range res.edges() {
+		if err := cw.Write([]string{e.from, e.to, fmt.Sprint(res.testOnly[e])}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// printTargetEdgeList prints the edges of the results of several targets as one edge list,
+// with the target of each edge in a first column.
+func printTargetEdgeList(w io.Writer, results []Result, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write([]string{"target", "from", "to", "test_only"}); err != nil {
+		return err
+	}
+	for _, res := range results {
+		for _, e := range res.edges() {
+			if err := cw.Write([]string{res.Target, e.from, e.to, fmt.Sprint(res.testOnly[e])}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var templateFuncs = texttemplate.FuncMap{
+	"join": strings.Join,
+}
+
+func printTemplate(w io.Writer, tmpl string, res Result) error {
+	if tmpl == "" {
+		return fmt.Errorf("--template is required for template format")
+	}
+	t, err := texttemplate.New("output").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("invalid template: %v", err)
+	}
+	return t.Execute(w, res)
+}
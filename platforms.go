@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// explainPlatforms explains the targets on every platform of --platforms and merges the
+// paths, recording the platforms each path exists on.
+func explainPlatforms(opts Opts, targetArgs []string) ([]Result, error) {
+	var merged []Result
+	index := make(map[string]int)
+	pathIndex := make([]map[string]int, 0)
+	for _, platform := range splitList(opts.Platforms) {
+		goos, goarch, ok := strings.Cut(platform, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("invalid platform %q, expecting os/arch", platform)
+		}
+		o := opts
+		o.GOOS, o.GOARCH = goos, goarch
+		infof("Analyzing platform %s...", platform)
+		results, err := explain(o, targetArgs)
+		if err == errNoTarget {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", platform, err)
+		}
+		for _, res := range results {
+			if res.subgraph || res.hasReport() {
+				return nil, errors.New("--platforms only supports listing paths")
+			}
+			key := res.Target + "\x00" + res.Root
+			i, ok := index[key]
+			if !ok {
+				i = len(merged)
+				index[key] = i
+				merged = append(merged, Result{Target: res.Target, Root: res.Root, testOnly: res.testOnly, packages: res.packages, licenses: res.licenses, statuses: res.statuses, Paths: [][]string{}, Platforms: [][]string{}})
+				pathIndex = append(pathIndex, make(map[string]int))
+			}
+			m := &merged[i]
+			m.Truncated = m.Truncated || res.Truncated
+			m.reached = m.reached || res.reached
+			for _, p := range res.Paths {
+				pathKey := strings.Join(p, "\x00")
+				j, ok := pathIndex[i][pathKey]
+				if !ok {
+					j = len(m.Paths)
+					pathIndex[i][pathKey] = j
+					m.Paths = append(m.Paths, p)
+					m.Platforms = append(m.Platforms, nil)
+					if res.TestOnly != nil {
+						m.TestOnly = append(m.TestOnly, m.isTestOnly(p))
+					}
+				}
+				m.Platforms[j] = append(m.Platforms[j], platform)
+			}
+		}
+	}
+	if len(merged) == 0 {
+		return nil, errNoTarget
+	}
+	return merged, nil
+}
+
+// PlatformPackage is a third-party package only built on some of the compared platforms,
+// with the shortest import chain to it on the first of them.
+type PlatformPackage struct {
+	Package   string   `json:"package"`
+	Platforms []string `json:"platforms"`
+	Path      []string `json:"path"`
+}
+
+type platformsCommand struct {
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// platformOnly loads the graph on every platform of --platforms and returns the third-party
+// packages missing from the graph of at least one of them.
+func platformOnly(opts Opts) ([]PlatformPackage, error) {
+	platforms := splitList(opts.Platforms)
+	if len(platforms) < 2 {
+		return nil, errors.New("at least two platforms must be given with --platforms")
+	}
+	byPackage := make(map[string]*PlatformPackage)
+	for _, platform := range platforms {
+		goos, goarch, ok := strings.Cut(platform, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("invalid platform %q, expecting os/arch", platform)
+		}
+		o := opts
+		o.GOOS, o.GOARCH = goos, goarch
+		infof("Loading platform %s...", platform)
+		g, err := loadGraph(o)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", platform, err)
+		}
+		for pkg, chain := range shortestChains(g.roots, g.forward) {
+			if m := g.pkgMap[pkg].Module; m == nil || m.Main {
+				continue
+			}
+			p, ok := byPackage[pkg]
+			if !ok {
+				p = &PlatformPackage{Package: pkg, Path: chain}
+				byPackage[pkg] = p
+			}
+			p.Platforms = append(p.Platforms, platform)
+		}
+	}
+	res := make([]PlatformPackage, 0)
+	for _, p := range byPackage {
+		if len(p.Platforms) < len(platforms) {
+			res = append(res, *p)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Package < res[j].Package })
+	return res, nil
+}
+
+func (c *platformsCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	pkgs, err := platformOnly(*c.opts)
+	if err != nil {
+		return err
+	}
+
+	return writeReport(c.parser, *c.opts, "platforms", Result{PlatformOnly: pkgs}, func(w io.Writer) error {
+		return printPlatformOnly(w, pkgs)
+	})
+}
+
+func printPlatformOnly(w io.Writer, pkgs []PlatformPackage) error {
+	fmt.Fprintln(w, "# platforms")
+	if len(pkgs) == 0 {
+		fmt.Fprintln(w, "every third-party package is built on all platforms")
+		return nil
+	}
+	for _, p := range pkgs {
+		fmt.Fprintf(w, "%s (%s)\n", p.Package, strings.Join(p.Platforms, ", "))
+		for _, pkg := range p.Path {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
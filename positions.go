@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// importDecl describes the import declarations of a package importing another.
+type importDecl struct {
+	// pos is the file:line of the first declaration in file order.
+	pos string
+	// kind is "blank" or "dot" if every declaration is a blank or a dot import, or else "".
+	kind string
+}
+
+// importDecls returns the import declarations of every import of the package. File names
+// are relative to the current directory when they are below it. Files which cannot be
+// parsed are ignored.
+func importDecls(p Package) map[string]importDecl {
+	wd, _ := os.Getwd()
+	res := make(map[string]importDecl)
+	for _, files := range [][]string{p.GoFiles, p.CgoFiles, p.TestGoFiles, p.XTestGoFiles} {
+		for _, name := range files {
+			path := filepath.Join(p.Dir, name)
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, path, nil, parser.ImportsOnly)
+			if err != nil {
+				continue
+			}
+			if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+			for _, spec := range f.Imports {
+				imp, err := strconv.Unquote(spec.Path.Value)
+				if err != nil {
+					continue
+				}
+				kind := ""
+				if spec.Name != nil && spec.Name.Name == "_" {
+					kind = "blank"
+				} else if spec.Name != nil && spec.Name.Name == "." {
+					kind = "dot"
+				}
+				if d, ok := res[imp]; ok {
+					if d.kind != kind {
+						d.kind = ""
+						res[imp] = d
+					}
+					continue
+				}
+				res[imp] = importDecl{pos: fmt.Sprintf("%s:%d", path, fset.Position(spec.Pos()).Line), kind: kind}
+			}
+		}
+	}
+	return res
+}
+
+// pathImports returns, aligned with the paths, the file:line of the import leading to
+// every package of a path from a package of the main module with positions, and the kind
+// of the blank and dot imports leading to them with kinds. Both are the empty string for
+// the first package and the imports not annotated.
+func pathImports(packages map[string]Package, paths [][]string, positions, kinds bool) ([][]string, [][]string) {
+	cache := make(map[string]map[string]importDecl)
+	var pos, kind [][]string
+	if positions {
+		pos = make([][]string, len(paths))
+	}
+	if kinds {
+		kind = make([][]string, len(paths))
+	}
+	for i, p := range paths {
+		if positions {
+			pos[i] = make([]string, len(p))
+		}
+		if kinds {
+			kind[i] = make([]string, len(p))
+		}
+		for j := 1; j < len(p); j++ {
+			from := packages[p[j-1]]
+			main := from.Module != nil && from.Module.Main
+			if !kinds && !main {
+				continue
+			}
+			if _, ok := cache[from.ImportPath]; !ok {
+				cache[from.ImportPath] = importDecls(from)
+			}
+			d := cache[from.ImportPath][p[j]]
+			if positions && main {
+				pos[i][j] = d.pos
+			}
+			if kinds {
+				kind[i][j] = d.kind
+			}
+		}
+	}
+	return pos, kind
+}
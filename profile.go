@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// stopProfiling stops the profiles started by startProfiling and writes them.
+var stopProfiling = func() {}
+
+// startProfiling starts the CPU profile of --cpuprofile, and arranges for the heap
+// profile of --memprofile to be written when profiling stops.
+func startProfiling(o Opts) error {
+	var cpu *os.File
+	if o.CPUProfile != "" {
+		f, err := os.Create(o.CPUProfile)
+		if err != nil {
+			return err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return err
+		}
+		cpu = f
+	}
+	stopProfiling = func() {
+		stopProfiling = func() {}
+		if cpu != nil {
+			pprof.StopCPUProfile()
+			cpu.Close()
+		}
+		if o.MemProfile != "" {
+			if err := writeHeapProfile(o.MemProfile); err != nil {
+				fmt.Fprintf(os.Stderr, "cannot write memory profile: %v\n", err)
+			}
+		}
+	}
+	return nil
+}
+
+func writeHeapProfile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exit writes the profiles before exiting with the code.
+func exit(code int) {
+	stopProfiling()
+	os.Exit(code)
+}
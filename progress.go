@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"time"
+)
+
+// progress counts the work done so far, updated atomically by the path search.
+var progress struct {
+	packages int64
+	nodes    int64
+	paths    int64
+}
+
+// interrupted is set to 1 by the first SIGINT, the path search then stops and the paths
+// found so far are printed.
+var interrupted int32
+
+func isInterrupted() bool {
+	return atomic.LoadInt32(&interrupted) == 1
+}
+
+// timedOut is set to 1, along with interrupted, when the --timeout expires.
+var timedOut int32
+
+func isTimedOut() bool {
+	return atomic.LoadInt32(&timedOut) == 1
+}
+
+// goContext bounds the go commands, it is canceled when the --timeout expires.
+var goContext = context.Background()
+
+// startTimeout starts the --timeout: the go commands are killed, and the path search
+// stops like on interrupt. The returned function releases the timer.
+func startTimeout(o Opts) func() {
+	if o.Timeout <= 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), o.Timeout)
+	goContext = ctx
+	timer := time.AfterFunc(o.Timeout, func() {
+		atomic.StoreInt32(&timedOut, 1)
+		atomic.StoreInt32(&interrupted, 1)
+	})
+	return func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// handleInterrupt makes the first SIGINT stop the path search instead of exiting, the
+// second one exits right away.
+func handleInterrupt() {
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt)
+	go func() {
+		<-c
+		atomic.StoreInt32(&interrupted, 1)
+		fmt.Fprintln(os.Stderr, "\ninterrupted, printing the paths found so far, interrupt again to exit")
+		<-c
+		os.Exit(130)
+	}()
+}
+
+// startProgress prints the progress counters on stderr until the returned function is
+// called.
+func startProgress() func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	print := func() {
+		fmt.Fprintf(os.Stderr, "\r%d packages loaded, %d nodes explored, %d paths found", atomic.LoadInt64(&progress.packages), atomic.LoadInt64(&progress.nodes), atomic.LoadInt64(&progress.paths))
+	}
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				print()
+			case <-done:
+				print()
+				fmt.Fprintln(os.Stderr)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// remoteModuleDir returns a throwaway module requiring module@version, created on first use
+// in the temporary directory and reused afterwards.
+func remoteModuleDir(module, version string) (string, error) {
+	dir := filepath.Join(os.TempDir(), "gomodwhy", module+"@"+version)
+	if _, err := os.Stat(filepath.Join(dir, "go.sum")); err == nil {
+		return dir, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module gomodwhy.remote\n"), 0o644); err != nil {
+		return "", err
+	}
+	cmd := goCommand("get", module+"@"+version)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("go get failed: %v\n\n%s\n%s", err, cmd.String(), stderr.String())
+	}
+	return dir, nil
+}
+
+// prepare resolves the options which change where go commands run. A module@version pattern
+// is downloaded into a throwaway module whose packages of the module are then loaded.
+func (o *Opts) prepare() error {
+	goDir, goModFile = o.Chdir, o.Modfile
+	patterns := o.patterns()
+	i := strings.LastIndex(strings.Join(patterns, " "), "@")
+	if i < 0 {
+		return nil
+	}
+	if len(patterns) > 1 {
+		return fmt.Errorf("a module@version pattern cannot be combined with other patterns")
+	}
+	if o.Modfile != "" {
+		return fmt.Errorf("--modfile cannot be used with a module@version pattern")
+	}
+	module, version := patterns[0][:i], patterns[0][i+1:]
+	infof("Downloading %s@%s...", module, version)
+	dir, err := remoteModuleDir(module, version)
+	if err != nil {
+		return err
+	}
+	goDir = dir
+	o.fromModule = module
+	o.Pattern = []string{module + "/..."}
+	return nil
+}
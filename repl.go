@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// replHelp describes the lines the repl accepts.
+const replHelp = `[options] <target-pkg>...  explain the targets, with the options for this query only
+set <options>              keep the options, e.g. set -d 2 --avoid golang.org/x/...
+reset                      drop the options kept by set
+show                       print the options kept by set
+help                       print this help
+quit                       exit, like end of input
+`
+
+// replCommand takes the path options, which are the defaults of every query.
+type replCommand struct {
+	PathOpts `group:"Path Options"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// Execute loads the packages once, then reads queries line by line and prints their
+// answers like serve does for --server clients. The options of the command line load the
+// packages and are the defaults of every query.
+func (c *replCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	if c.opts.Mode == "module" || len(c.opts.Platforms) > 0 {
+		return errors.New("repl does not support --mode module and --platforms")
+	}
+	l, err := loadPackages(*c.opts)
+	if err != nil {
+		return err
+	}
+	interactive := isTerminal(os.Stdin)
+	if interactive {
+		fmt.Fprintf(os.Stderr, "Loaded %d packages, type help for the commands\n", len(l.packages))
+	}
+	var kept []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		if interactive {
+			fmt.Fprint(os.Stderr, "gomodwhy> ")
+		}
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		words := strings.Fields(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "quit", "exit":
+			return nil
+		case "help":
+			fmt.Print(replHelp)
+			continue
+		case "reset":
+			kept = nil
+			continue
+		case "show":
+			fmt.Println(strings.Join(kept, " "))
+			continue
+		case "set":
+			if targets, err := parseQuery(words[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+			} else if len(targets) > 0 {
+				fmt.Fprintf(os.Stderr, "set only takes options, not %s\n", strings.Join(targets, " "))
+			} else {
+				kept = append(kept, words[1:]...)
+			}
+			continue
+		}
+		targets, err := parseQuery(words)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			continue
+		}
+		if len(targets) == 0 {
+			fmt.Fprintln(os.Stderr, "no target given, type help for the commands")
+			continue
+		}
+		queryArgs := append(append(c.opts.args[:len(c.opts.args):len(c.opts.args)], kept...), words...)
+		a, err := answerQuery(*c.opts, query{Args: queryArgs, Targets: targets, Color: c.opts.useColor(os.Stdout)}, l)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+			continue
+		}
+		fmt.Print(a.Output)
+		if a.Failed != "" {
+			fmt.Fprintln(os.Stderr, a.Failed)
+		}
+	}
+}
+
+// parseQuery checks the options of a repl line and returns its targets.
+func parseQuery(words []string) ([]string, error) {
+	var opts Opts
+	return newQueryParser(&opts, flags.PassDoubleDash).ParseArgs(words)
+}
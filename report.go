@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+)
+
+type reportCommand struct {
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// inventory returns every third-party module of the build with its shortest import chain.
+func inventory(g *graph) []ModuleChain {
+	res := make([]ModuleChain, 0)
+	for _, c := range moduleChains(g) {
+		if g.pkgMap[c.Path[len(c.Path)-1]].Module.Main {
+			continue
+		}
+		res = append(res, c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Module < res[j].Module })
+	return res
+}
+
+func (c *reportCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	g, err := loadGraph(*c.opts)
+	if err != nil {
+		return err
+	}
+	modules := inventory(g)
+
+	if outputFormat(c.parser, *c.opts) == "markdown" {
+		return writeOutput(*c.opts, func(w io.Writer) error {
+			return printInventoryMarkdown(w, g.root, modules)
+		})
+	}
+	return writeReport(c.parser, *c.opts, "report", Result{Root: g.root, Inventory: modules}, func(w io.Writer) error {
+		return printInventory(w, modules)
+	})
+}
+
+func printInventory(w io.Writer, modules []ModuleChain) error {
+	fmt.Fprintln(w, "# report")
+	fmt.Fprintf(w, "%d third-party module(s)\n", len(modules))
+	for _, m := range modules {
+		fmt.Fprintf(w, "\n## %s\n", m.label())
+		for _, pkg := range m.Path {
+			fmt.Fprintln(w, pkg)
+		}
+	}
+	return nil
+}
+
+func printInventoryMarkdown(w io.Writer, root string, modules []ModuleChain) error {
+	fmt.Fprintf(w, "# Third-party modules of `%s`\n\n", root)
+	fmt.Fprintf(w, "%d third-party module(s), each with its shortest import chain.\n", len(modules))
+	for _, m := range modules {
+		fmt.Fprintf(w, "\n## `%s`\n\n", m.label())
+		fmt.Fprintln(w, "```")
+		for _, pkg := range m.Path {
+			fmt.Fprintln(w, pkg)
+		}
+		fmt.Fprintln(w, "```")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"regexp"
+)
+
+// pseudoVersion matches pseudo-versions like v0.0.0-20191109021931-daa7c04131f5, see
+// https://go.dev/ref/mod#pseudo-versions.
+var pseudoVersion = regexp.MustCompile(`^v[0-9]+\.(0\.0-|\d+\.\d+-([^+]*\.)?0\.)\d{14}-[A-Za-z0-9]+(\+[0-9A-Za-z-]+)?$`)
+
+// versionStatuses returns the status of the selected version of every third-party module
+// which is a pseudo-version or retracted, keyed by module path. Retractions come from the
+// go.mod of the latest version of each module, a failure to fetch them is only a warning.
+func versionStatuses(packages []Package) map[string]string {
+	statuses := make(map[string]string)
+	for _, p := range packages {
+		if m := p.Module; m != nil && !m.Main && pseudoVersion.MatchString(m.Version) {
+			statuses[m.Path] = "pseudo-version"
+		}
+	}
+	modules, err := listModules("-retracted", "all")
+	if err != nil {
+		warnf("cannot check retracted versions: %v", err)
+		return statuses
+	}
+	for _, m := range modules {
+		if len(m.Retracted) == 0 {
+			continue
+		}
+		if statuses[m.Path] != "" {
+			statuses[m.Path] += ", retracted"
+		} else {
+			statuses[m.Path] = "retracted"
+		}
+	}
+	return statuses
+}
+
+// versionStatusOf returns the version status of the module of pkg, or "".
+func (res Result) versionStatusOf(pkg string) string {
+	if p := res.packages[pkg]; p.Module != nil {
+		return res.statuses[p.Module.Path]
+	}
+	return ""
+}
+
+// pathVersionStatuses returns the version status of the packages on the paths.
+func (res Result) pathVersionStatuses() map[string]string {
+	statuses := make(map[string]string)
+	for _, p := range res.Paths {
+		for _, pkg := range p {
+			if s := res.versionStatusOf(pkg); s != "" {
+				statuses[pkg] = s
+			}
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// ModuleScan is the shortest import chain to a target from the packages of one module of a
+// scanned directory tree.
+type ModuleScan struct {
+	Dir    string   `json:"dir"`
+	Module string   `json:"module"`
+	Target string   `json:"target"`
+	Path   []string `json:"path"`
+}
+
+type scanCommand struct {
+	Dir  string `long:"dir" description:"directory tree to discover go.mod files in" default:"."`
+	Args struct {
+		Targets []string `positional-arg-name:"target-pkg"`
+	} `positional-args:"yes" required:"yes"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// findModules returns the directories below root containing a go.mod file, skipping vendor,
+// testdata and hidden directories like the go command does.
+func findModules(root string) ([]string, error) {
+	var dirs []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() == "go.mod" {
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		return nil
+	})
+	return dirs, err
+}
+
+// scanModule explains the targets from every package of the module in dir.
+func scanModule(opts Opts, dir string, targets []string) ([]ModuleScan, error) {
+	goDir, goModFile = dir, ""
+	mod, err := readModFile()
+	if err != nil {
+		return nil, err
+	}
+	opts.Pattern = []string{mod.Module.Path + "/..."}
+	opts.fromModule = mod.Module.Path
+	opts.From = ""
+	results, err := explain(opts, targets)
+	if errors.Is(err, errNoTarget) || errors.Is(err, errNoPackage) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var res []ModuleScan
+	for _, r := range results {
+		scan := ModuleScan{Dir: dir, Module: mod.Module.Path, Target: r.Target}
+		for _, p := range r.Paths {
+			if scan.Path == nil || len(p) < len(scan.Path) {
+				scan.Path = p
+			}
+		}
+		// A single package path is the target itself, the module only provides it.
+		if len(scan.Path) > 1 {
+			res = append(res, scan)
+		}
+	}
+	return res, nil
+}
+
+func (c *scanCommand) Execute(args []string) error {
+	root, err := filepath.Abs(c.Dir)
+	if err != nil {
+		return err
+	}
+	dirs, err := findModules(root)
+	if err != nil {
+		return err
+	}
+	infof("Found %d modules under %s", len(dirs), root)
+	scans := make([]ModuleScan, 0)
+	for _, dir := range dirs {
+		infof("Scanning %s...", dir)
+		res, err := scanModule(*c.opts, dir, c.Args.Targets)
+		if err != nil {
+			warnf("skipping %s: %v", dir, err)
+			continue
+		}
+		for i := range res {
+			if rel, err := filepath.Rel(root, res[i].Dir); err == nil {
+				res[i].Dir = rel
+			}
+		}
+		scans = append(scans, res...)
+	}
+
+	return writeReport(c.parser, *c.opts, "scan", Result{Target: strings.Join(c.Args.Targets, ","), Scan: scans}, func(w io.Writer) error {
+		return printScan(w, len(dirs), scans)
+	})
+}
+
+// printScan prints, for every target, the modules importing it with their shortest chain.
+func printScan(w io.Writer, modules int, scans []ModuleScan) error {
+	var targets []string
+	byTarget := make(map[string][]ModuleScan)
+	for _, s := range scans {
+		if len(s.Path) == 0 {
+			continue
+		}
+		if _, ok := byTarget[s.Target]; !ok {
+			targets = append(targets, s.Target)
+		}
+		byTarget[s.Target] = append(byTarget[s.Target], s)
+	}
+	if len(targets) == 0 {
+		fmt.Fprintf(w, "none of the %d module(s) imports the target\n", modules)
+		return nil
+	}
+	for i, target := range targets {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "# %s\n", target)
+		fmt.Fprintf(w, "imported by %d of %d module(s)\n", len(byTarget[target]), modules)
+		for _, s := range byTarget[target] {
+			fmt.Fprintf(w, "\n## %s (%s)\n", s.Module, s.Dir)
+			for _, pkg := range s.Path {
+				fmt.Fprintln(w, pkg)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// Search lists the packages and modules of the loaded graph matching a keyword.
+type Search struct {
+	Packages []string `json:"packages"`
+	Modules  []string `json:"modules"`
+}
+
+type searchCommand struct {
+	Args struct {
+		Keyword string `positional-arg-name:"keyword"`
+	} `positional-args:"yes" required:"yes"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// search matches the keyword case-insensitively against package and module paths.
+func search(g *graph, keyword string) *Search {
+	keyword = strings.ToLower(keyword)
+	res := &Search{Packages: []string{}, Modules: []string{}}
+	modules := make(map[string]bool)
+	for _, p := range g.packages {
+		if strings.Contains(strings.ToLower(p.ImportPath), keyword) {
+			res.Packages = append(res.Packages, p.ImportPath)
+		}
+		if m := p.Module; m != nil && !modules[m.Path] && strings.Contains(strings.ToLower(m.Path), keyword) {
+			modules[m.Path] = true
+			if m.Version == "" {
+				res.Modules = append(res.Modules, m.Path)
+			} else {
+				res.Modules = append(res.Modules, m.Path+"@"+m.Version)
+			}
+		}
+	}
+	sort.Strings(res.Packages)
+	sort.Strings(res.Modules)
+	return res
+}
+
+func (c *searchCommand) Execute(args []string) error {
+	g, err := loadGraph(*c.opts)
+	if err != nil {
+		return err
+	}
+	res := search(g, c.Args.Keyword)
+
+	return writeReport(c.parser, *c.opts, "search", Result{Target: c.Args.Keyword, Root: g.root, Search: res}, func(w io.Writer) error {
+		return printSearch(w, c.Args.Keyword, res)
+	})
+}
+
+func printSearch(w io.Writer, keyword string, res *Search) error {
+	fmt.Fprintf(w, "# %s\n", keyword)
+	if len(res.Packages) == 0 && len(res.Modules) == 0 {
+		fmt.Fprintln(w, "no package or module found")
+		return nil
+	}
+	if len(res.Modules) > 0 {
+		fmt.Fprintf(w, "modules (%d):\n", len(res.Modules))
+		for _, m := range res.Modules {
+			fmt.Fprintf(w, "  %s\n", m)
+		}
+	}
+	if len(res.Packages) > 0 {
+		fmt.Fprintf(w, "packages (%d):\n", len(res.Packages))
+		for _, pkg := range res.Packages {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// defaultSocket returns the unix socket serve listens on unless --socket is given, in the
+// runtime directory of the user, or else in the gomodwhy directory of the user cache, so
+// that users do not share it.
+func defaultSocket() (string, error) {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gomodwhy.sock"), nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "gomodwhy")
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gomodwhy.sock"), nil
+}
+
+// removeStaleSocket removes the socket left by a server which is gone, failing if a server
+// still listens on it.
+func removeStaleSocket(socket string) error {
+	if _, err := os.Stat(socket); err != nil {
+		return nil
+	}
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", socket)
+	}
+	return os.Remove(socket)
+}
+
+// query is a request of a client to the server: the command line options, parsed again by
+// the server, the targets and whether to colorize the output.
+type query struct {
+	Args    []string `json:"args"`
+	Targets []string `json:"targets"`
+	Color   bool     `json:"color"`
+}
+
+// answer is the response of the server to a query.
+type answer struct {
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"`
+	// Failed is the reason for the client to fail with --fail-if-found or --fail-if-missing.
+	Failed string `json:"failed,omitempty"`
+}
+
+type serveCommand struct {
+	Socket string `long:"socket" description:"unix socket to listen on, gomodwhy.sock in $XDG_RUNTIME_DIR or the user cache directory by default"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+func (c *serveCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	if c.opts.Mode == "module" || len(c.opts.Platforms) > 0 {
+		return errors.New("serve does not support --mode module and --platforms")
+	}
+	l, err := loadPackages(*c.opts)
+	if err != nil {
+		return err
+	}
+	socket := c.Socket
+	if socket == "" {
+		if socket, err = defaultSocket(); err != nil {
+			return err
+		}
+	}
+	if err := removeStaleSocket(socket); err != nil {
+		return err
+	}
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer ln.Close()
+	// Only the user may send queries.
+	if err := os.Chmod(socket, 0o600); err != nil {
+		return err
+	}
+	infof("serving %d packages on %s", len(l.packages), socket)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go c.handle(conn, l)
+	}
+}
+
+// handle answers the query of a client connection.
+func (c *serveCommand) handle(conn net.Conn, l *loaded) {
+	defer conn.Close()
+	var q query
+	var a answer
+	if err := json.NewDecoder(conn).Decode(&q); err != nil {
+		a.Error = err.Error()
+	} else if a, err = answerQuery(*c.opts, q, l); err != nil {
+		a = answer{Error: err.Error()}
+	}
+	json.NewEncoder(conn).Encode(a)
+}
+
+// answerQuery runs a query on the loaded packages. The options loading the packages, such
+// as the pattern, tags or platform, are the ones of base, those of serve or repl.
+func answerQuery(base Opts, q query, l *loaded) (answer, error) {
+	var opts Opts
+	parser := newQueryParser(&opts, flags.IgnoreUnknown)
+	if _, err := parser.ParseArgs(q.Args); err != nil {
+		return answer{}, err
+	}
+	if opts.Stream || len(opts.Platforms) > 0 {
+		return answer{}, errors.New("--stream and --platforms are not supported with --server and in repl")
+	}
+	opts.Pattern, opts.Mode, opts.KeepGoing, opts.Mod, opts.Tags = base.Pattern, base.Mode, base.KeepGoing, base.Mod, base.Tags
+	opts.GOOS, opts.GOARCH, opts.IncludeTools, opts.IncludeTest = base.GOOS, base.GOARCH, base.IncludeTools, base.IncludeTest
+	opts.fromModule, opts.Verbose = base.fromModule, false
+	opts.cmdline = q.Args
+	if opts.TestOnly && opts.ProdOnly {
+		return answer{}, errors.New("--test-only and --prod-only are mutually exclusive")
+	}
+	g, err := buildGraph(opts, l)
+	if err != nil {
+		return answer{}, err
+	}
+	if opts.Compat {
+		return answer{Output: compatWhy(opts, g, q.Targets)}, nil
+	}
+	results, err := explainGraph(opts, g, q.Targets)
+	if err == errNoTarget {
+		return answer{}, fmt.Errorf("no package matches %s", strings.Join(q.Targets, ", "))
+	}
+	if err != nil {
+		return answer{}, err
+	}
+	var buf bytes.Buffer
+	popts := printOptions{Format: outputFormat(parser, opts), Template: opts.Template, Color: q.Color, ASCII: opts.ASCII, Compress: opts.Compress, Versions: opts.Versions, Abbrev: opts.Abbrev, Aliases: opts.Alias}
+	if err := printResults(&buf, popts, strings.Join(q.Targets, ", "), results); err != nil {
+		return answer{}, err
+	}
+	a := answer{Output: buf.String()}
+	if err := opts.checkFound(results); err != nil {
+		a.Failed = err.Error()
+	}
+	return a, nil
+}
+
+// newQueryParser returns the parser of the options of a query into opts, the path options
+// included.
+func newQueryParser(opts *Opts, options flags.Options) *flags.Parser {
+	parser := flags.NewParser(opts, options)
+	parser.AddGroup("Path Options", "", &opts.PathOpts)
+	return parser
+}
+
+// ask sends the query to the server listening on socket and returns its answer.
+func ask(socket string, q query) (answer, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return answer{}, err
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(q); err != nil {
+		return answer{}, err
+	}
+	var a answer
+	if err := json.NewDecoder(conn).Decode(&a); err != nil {
+		return answer{}, err
+	}
+	if a.Error != "" {
+		return answer{}, errors.New(a.Error)
+	}
+	return a, nil
+}
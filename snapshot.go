@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+)
+
+// snapshot is the serialized form of the loaded packages, written by --save-graph and
+// read back by --load-graph.
+type snapshot struct {
+	Packages  []Package
+	Roots     []string
+	Workspace []Module
+	ToolEdges [][2]string
+}
+
+func saveSnapshot(file string, l *loaded) error {
+	s := snapshot{Packages: l.packages, Roots: l.roots, Workspace: l.workspace}
+	for e := range l.toolEdges {
+		s.ToolEdges = append(s.ToolEdges, [2]string{e.from, e.to})
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(s); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot save graph: %v", err)
+	}
+	return f.Close()
+}
+
+func loadSnapshot(file string) (*loaded, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var s snapshot
+	if err := gob.NewDecoder(f).Decode(&s); err != nil {
+		return nil, fmt.Errorf("cannot load graph %s: %v", file, err)
+	}
+	if len(s.Packages) == 0 {
+		return nil, errNoPackage
+	}
+	l := &loaded{packages: s.Packages, roots: s.Roots, workspace: s.Workspace}
+	if len(s.ToolEdges) > 0 {
+		l.toolEdges = make(map[edge]bool, len(s.ToolEdges))
+		for _, e := range s.ToolEdges {
+			l.toolEdges[edge{from: e[0], to: e[1]}] = true
+		}
+	}
+	return l, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// pathStream prints the paths of every analyzed target as soon as they are found. With a
+// page size, it stops after every page: on a terminal until enter is pressed, otherwise
+// for good, and the first offset paths are skipped.
+type pathStream struct {
+	w     io.Writer
+	opts  printOptions
+	paint func(string) string
+	count int
+
+	pageSize int
+	offset   int
+	skipped  int
+	prompt   *bufio.Reader // reads the answers to the page prompt, nil if not interactive
+}
+
+func (s *pathStream) begin(res Result) {
+	s.paint = res.painter(s.opts)
+	s.count, s.skipped = 0, 0
+	fmt.Fprintf(s.w, "# %s\n", s.paint(res.Target))
+}
+
+// path prints the paths of res, and returns false once the search should stop.
+func (s *pathStream) path(res Result) bool {
+	for i := range res.Paths {
+		if s.skipped < s.offset {
+			s.skipped++
+			continue
+		}
+		if s.pageSize > 0 && s.count > 0 && s.count%s.pageSize == 0 && !s.more() {
+			return false
+		}
+		printPath(s.w, res, i, s.paint)
+		s.count++
+	}
+	return true
+}
+
+// more asks whether to print the next page.
+func (s *pathStream) more() bool {
+	if s.prompt == nil {
+		return false
+	}
+	fmt.Fprint(os.Stderr, "-- press enter for more, q to quit --")
+	line, err := s.prompt.ReadString('\n')
+	return err == nil && strings.TrimSpace(line) != "q"
+}
+
+func (s *pathStream) end(res Result) {
+	if s.count == 0 {
+		fmt.Fprintln(s.w, "no import chain found")
+	}
+	if res.TimedOut {
+		fmt.Fprintf(s.w, "… truncated by timeout, only the %d import chains found so far are shown\n", s.count)
+	} else if res.Truncated && s.pageSize > 0 {
+		fmt.Fprintf(s.w, "… more import chains exist, use --offset %d for the next page\n", s.offset+s.count)
+	} else if res.Truncated {
+		fmt.Fprintf(s.w, "… more import chains exist, only the first %d are shown\n", s.count)
+	}
+}
+
+// checkStream returns an error if the options need every path before printing any.
+func (o Opts) checkStream(format string) error {
+	if format != "text" {
+		return fmt.Errorf("format %s is not supported with --stream", format)
+	}
+	conflicts := map[string]bool{
+		"--shortest":         o.Shortest,
+		"--subgraph":         o.Subgraph,
+		"--dominators":       o.Dominators,
+		"--explain-cut":      o.ExplainCut,
+		"--who-imports":      o.WhoImports,
+		"--weight":           o.Weight,
+		"--reverse":          o.Reverse,
+		"--summary":          o.Summary,
+		"--group-by":         o.GroupBy != "none",
+		"--forks":            o.Forks,
+		"--rank":             o.Rank,
+		"--license-summary":  o.LicenseSummary,
+		"--compress":         o.Compress,
+		"--count-only":       o.CountOnly,
+		"--first-party-only": o.FirstPartyOnly,
+		"--granularity":      o.Granularity != "package",
+		"--platforms":        len(o.Platforms) > 0,
+		"--sort":             o.Sort != "length",
+	}
+	var flags []string
+	for flag, set := range conflicts {
+		if set {
+			flags = append(flags, flag)
+		}
+	}
+	if len(flags) > 0 {
+		sort.Strings(flags)
+		return fmt.Errorf("--stream cannot be used with %s", strings.Join(flags, ", "))
+	}
+	return nil
+}
+
+// streamPaths calls emit with every path from start to end as it is found, walking the
+// importers back from end with an explicit stack instead of collecting the paths. With a
+// positive depth, the paths are cut to their last depth hops like in allPaths. With a
+// positive limit, it stops after limit paths and returns true if more paths exist, like
+// when emit returns false.
+func streamPaths(start string, end string, forward map[string][]string, depth int, limit int, emit func([]string) bool) bool {
+	live := reachable(start, forward, nil)
+	reversed := make(map[string][]string)
+	for k, v := range forward {
+		if !live[k] {
+			continue
+		}
+		for _, next := range v {
+			reversed[next] = append(reversed[next], k)
+		}
+	}
+	if !live[end] {
+		return false
+	}
+	// Visit importers in a fixed order, so that --offset resumes where a previous run stopped.
+	for _, importers := range reversed {
+		sort.Strings(importers)
+	}
+
+	// stack holds the path walked back from end, next the index of the next importer to
+	// visit for every package of it.
+	stack := []string{end}
+	next := []int{0}
+	onPath := map[string]bool{end: true}
+	count := 0
+	for len(stack) > 0 {
+		if isInterrupted() {
+			return true
+		}
+		top := len(stack) - 1
+		node := stack[top]
+		if node == start || (depth > 0 && top == depth) {
+			if limit > 0 && count == limit {
+				return true
+			}
+			path := make([]string, len(stack))
+			for i, pkg := range stack {
+				path[len(stack)-1-i] = pkg
+			}
+			if !emit(path) {
+				return true
+			}
+			count++
+			atomic.AddInt64(&progress.paths, 1)
+		} else if next[top] < len(reversed[node]) {
+			importer := reversed[node][next[top]]
+			next[top]++
+			if !onPath[importer] {
+				atomic.AddInt64(&progress.nodes, 1)
+				stack = append(stack, importer)
+				next = append(next, 0)
+				onPath[importer] = true
+			}
+			continue
+		}
+		delete(onPath, node)
+		stack, next = stack[:top], next[:top]
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// SumReport explains why a go.sum entry exists.
+type SumReport struct {
+	Module        string   `json:"module"`
+	Version       string   `json:"version"`
+	BuildRequired bool     `json:"build_required"`
+	Packages      []string `json:"packages"`
+	Path          []string `json:"path"`
+}
+
+type sumCommand struct {
+	Args struct {
+		Entry []string `positional-arg-name:"module@version"`
+	} `positional-args:"yes" required:"yes"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// parseSumEntry accepts module@version, "module version" or a whole go.sum line.
+func parseSumEntry(fields []string) (module, version string, err error) {
+	if len(fields) == 1 {
+		module, version = splitVersion(fields[0])
+	} else {
+		module, version = fields[0], fields[1]
+	}
+	version = strings.TrimSuffix(version, "/go.mod")
+	if module == "" || version == "" {
+		return "", "", fmt.Errorf("invalid go.sum entry %q, expecting module@version", strings.Join(fields, " "))
+	}
+	return module, version, nil
+}
+
+// explainSum reports whether packages of the module version are built, otherwise the
+// entry only exists because go.mod files of the module graph are needed to select versions.
+func explainSum(g *graph, module, version string, modGraph map[string][]string, mainModules []string) *SumReport {
+	report := &SumReport{Module: module, Version: version, Packages: []string{}, Path: []string{}}
+	for _, p := range g.packages {
+		if p.Module != nil && p.Module.Path == module && p.Module.Version == version {
+			report.Packages = append(report.Packages, p.ImportPath)
+		}
+	}
+	sort.Strings(report.Packages)
+	report.BuildRequired = len(report.Packages) > 0
+	if chain := requirementChain(mainModules, module+"@"+version, modGraph); chain != nil {
+		report.Path = chain
+	}
+	return report
+}
+
+func (c *sumCommand) Execute(args []string) error {
+	module, version, err := parseSumEntry(c.Args.Entry)
+	if err != nil {
+		return err
+	}
+	g, err := loadGraph(*c.opts)
+	if err != nil {
+		return err
+	}
+	infof("Executing go mod graph command to get module requirements...")
+	modGraph, err := runGoModGraph()
+	if err != nil {
+		return err
+	}
+	mainModules, err := mainModulePaths()
+	if err != nil {
+		return err
+	}
+	report := explainSum(g, module, version, modGraph, mainModules)
+
+	return writeReport(c.parser, *c.opts, "sum", Result{Target: module + "@" + version, Root: chainRoot(report.Path, mainModules), Sum: report}, func(w io.Writer) error {
+		return printSum(w, report)
+	})
+}
+
+func printSum(w io.Writer, report *SumReport) error {
+	fmt.Fprintf(w, "# %s@%s\n", report.Module, report.Version)
+	switch {
+	case report.BuildRequired:
+		fmt.Fprintf(w, "build-required, %d package(s) of it are built:\n", len(report.Packages))
+		for _, pkg := range report.Packages {
+			fmt.Fprintf(w, "  %s\n", pkg)
+		}
+	case len(report.Path) > 0:
+		fmt.Fprintln(w, "graph-required, only its go.mod is needed for version selection")
+	default:
+		fmt.Fprintln(w, "not in the module graph, the entry can be removed by go mod tidy")
+		return nil
+	}
+	if len(report.Path) > 0 {
+		fmt.Fprintln(w, "requirement chain:")
+		for _, node := range report.Path {
+			fmt.Fprintf(w, "  %s\n", node)
+		}
+	}
+	return nil
+}
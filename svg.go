@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+const (
+	svgCharWidth  = 7
+	svgNodeHeight = 24
+	svgPaddingX   = 10
+	svgLayerGap   = 60
+	svgRowGap     = 16
+	svgMargin     = 20
+)
+
+type svgNode struct {
+	name  string
+	layer int
+	x, y  int
+	width int
+}
+
+// layoutLayers assigns every package on the paths to a layer using longest-path layering,
+// so that edges always point from a lower layer to a higher one in acyclic graphs.
+func layoutLayers(edges []edge, nodes []string) map[string]int {
+	layers := make(map[string]int, len(nodes))
+	for _, n := range nodes {
+		layers[n] = 0
+	}
+	// Bound the relaxation by the number of nodes in case test imports introduce cycles.
+	for i := 0; i < len(nodes); i++ {
+		changed := false
+		for _, e := range edges {
+			if layers[e.to] < layers[e.from]+1 {
+				layers[e.to] = layers[e.from] + 1
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return layers
+}
+
+func printSVG(w io.Writer, res Result) error {
+	edges := res.edges()
+	var names []string
+	seen := make(map[string]bool)
+	for _, e := range edges {
+		for _, item := range []string{e.from, e.to} {
+			if !seen[item] {
+				seen[item] = true
+				names = append(names, item)
+			}
+		}
+	}
+	if len(names) == 0 {
+		names = res.targets()
+	}
+
+	layers := layoutLayers(edges, names)
+	var columns [][]*svgNode
+	nodes := make(map[string]*svgNode, len(names))
+	for _, name := range names {
+		n := &svgNode{name: name, layer: layers[name], width: len(name)*svgCharWidth + 2*svgPaddingX}
+		for len(columns) <= n.layer {
+			columns = append(columns, nil)
+		}
+		columns[n.layer] = append(columns[n.layer], n)
+		nodes[name] = n
+	}
+
+	x, width, height := svgMargin, 0, 0
+	for _, column := range columns {
+		columnWidth := 0
+		for i, n := range column {
+			n.x = x
+			n.y = svgMargin + i*(svgNodeHeight+svgRowGap)
+			if n.width > columnWidth {
+				columnWidth = n.width
+			}
+			if n.y+svgNodeHeight+svgMargin > height {
+				height = n.y + svgNodeHeight + svgMargin
+			}
+		}
+		x += columnWidth + svgLayerGap
+		width = x - svgLayerGap + svgMargin
+	}
+
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" font-family=\"monospace\" font-size=\"12\">\n", width, height, width, height)
+	fmt.Fprintln(w, `<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto"><path d="M0,0L10,5L0,10z" fill="#555"/></marker></defs>`)
+	fmt.Fprintln(w, `<rect width="100%" height="100%" fill="white"/>`)
+	for _, e := range edges {
+		from, to := nodes[e.from], nodes[e.to]
+		fmt.Fprintf(w, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#555\" marker-end=\"url(#arrow)\"/>\n",
+			from.x+from.width, from.y+svgNodeHeight/2, to.x, to.y+svgNodeHeight/2)
+	}
+	for _, name := range names {
+		n := nodes[name]
+		fill := "#f6f8fa"
+		if name == res.Root {
+			fill = "#dafbe1"
+		} else if res.isTarget(name) {
+			fill = "#ffebe9"
+		}
+		fmt.Fprintf(w, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" rx=\"4\" fill=\"%s\" stroke=\"#57606a\"/>\n", n.x, n.y, n.width, svgNodeHeight, fill)
+		fmt.Fprintf(w, "<text x=\"%d\" y=\"%d\">%s</text>\n", n.x+svgPaddingX, n.y+svgNodeHeight/2+4, template.HTMLEscapeString(name))
+	}
+	fmt.Fprintln(w, "</svg>")
+	return nil
+}
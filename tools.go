@@ -0,0 +1,62 @@
+package main
+
+// loadTools reloads the packages with the tools build tag, which tools.go files use to
+// blank-import tool dependencies, and the packages of the go.mod tool directives. It returns
+// the packages, the root package last, and the edges which only exist because of tools.
+// Tool directives are not imports, they become edges from the root package.
+func loadTools(opts Opts, patterns []string, packages []Package) ([]Package, map[edge]bool, error) {
+	mod, err := readModFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	root := packages[len(packages)-1].ImportPath
+	o := opts
+	if o.Tags == "" {
+		o.Tags = "tools"
+	} else {
+		o.Tags += ",tools"
+	}
+	toolPatterns := append([]string{}, patterns...)
+	for _, t := range mod.Tool {
+		toolPatterns = append(toolPatterns, t.Path)
+	}
+	infof("Executing go list command to get tool dependency information...")
+	all, err := runGoList(toolPatterns, o.includeTest(), o.listFlags(), o.goEnv())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	edges := make(map[edge]bool)
+	for _, p := range packages {
+		for _, imp := range p.Imports {
+			edges[edge{from: p.ImportPath, to: imp}] = true
+		}
+	}
+	toolEdges := make(map[edge]bool)
+	res := make([]Package, 0, len(all))
+	var rootPkg *Package
+	for i := range all {
+		p := all[i]
+		if p.ImportPath == root {
+			for _, t := range mod.Tool {
+				if !contains(p.Imports, t.Path) {
+					p.Imports = append(p.Imports, t.Path)
+				}
+			}
+		}
+		for _, imp := range p.Imports {
+			if e := (edge{from: p.ImportPath, to: imp}); !edges[e] {
+				toolEdges[e] = true
+			}
+		}
+		if p.ImportPath == root {
+			rootPkg = &p
+			continue
+		}
+		res = append(res, p)
+	}
+	if rootPkg != nil {
+		res = append(res, *rootPkg)
+	}
+	return res, toolEdges, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// Requirement is a require directive of go.mod.
+type Requirement struct {
+	Path     string `json:"path"`
+	Version  string `json:"version"`
+	Indirect bool   `json:"indirect"`
+}
+
+type unusedCommand struct {
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// goMod is the part of the main module's go.mod used by gomodwhy.
+type goMod struct {
+	Module struct {
+		Path string
+	}
+	Require []Requirement
+	Tool    []struct {
+		Path string
+	}
+}
+
+// readModFile parses the main module's go.mod.
+func readModFile() (*goMod, error) {
+	cmd := goCommand("mod", "edit", "-json")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("go mod edit failed: %v\n\n%s\n%s", err, cmd.String(), exitErr.Stderr)
+		}
+		return nil, fmt.Errorf("go mod edit failed: %v\n\n%s", err, cmd.String())
+	}
+	var mod goMod
+	if err := json.Unmarshal(output, &mod); err != nil {
+		return nil, fmt.Errorf("go mod edit failed: %v\n\n%s", err, cmd.String())
+	}
+	return &mod, nil
+}
+
+// unusedRequirements returns the requirements no package reachable from the main module
+// belongs to.
+func unusedRequirements(g *graph, requires []Requirement) []Requirement {
+	used := make(map[string]bool)
+	for _, p := range g.packages {
+		if p.Module == nil || !p.Module.Main {
+			continue
+		}
+		for pkg := range reachable(p.ImportPath, g.forward, nil) {
+			if m := g.pkgMap[pkg].Module; m != nil {
+				used[m.Path] = true
+			}
+		}
+	}
+	unused := make([]Requirement, 0)
+	for _, req := range requires {
+		if !used[req.Path] {
+			unused = append(unused, req)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool { return unused[i].Path < unused[j].Path })
+	return unused
+}
+
+func (c *unusedCommand) Execute(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %v", args)
+	}
+	opts := *c.opts
+	if !explicitOption(c.parser, "pattern") {
+		// A requirement used by any package of the main module is used, not only by the
+		// package of the current directory. Workspaces already load every module.
+		workspace, err := workspaceModules()
+		if err != nil {
+			return err
+		}
+		if len(workspace) == 0 {
+			opts.Pattern = []string{"./..."}
+		}
+	}
+	g, err := loadGraph(opts)
+	if err != nil {
+		return err
+	}
+	mod, err := readModFile()
+	if err != nil {
+		return err
+	}
+	requires := mod.Require
+	unused := unusedRequirements(g, requires)
+	infof("Found %d of %d requirements without any import path", len(unused), len(requires))
+
+	return writeReport(c.parser, opts, "unused", Result{Root: g.root, Unused: unused}, func(w io.Writer) error {
+		return printUnused(w, unused)
+	})
+}
+
+func printUnused(w io.Writer, unused []Requirement) error {
+	fmt.Fprintln(w, "# unused")
+	if len(unused) == 0 {
+		fmt.Fprintln(w, "every requirement is imported")
+		return nil
+	}
+	for _, req := range unused {
+		if req.Indirect {
+			fmt.Fprintf(w, "%s %s // indirect\n", req.Path, req.Version)
+		} else {
+			fmt.Fprintf(w, "%s %s\n", req.Path, req.Version)
+		}
+	}
+	return nil
+}
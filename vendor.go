@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// vendoredModules returns the module paths listed in vendor/modules.txt of the main module
+// in dir, or nil without vendor directory.
+func vendoredModules(dir string) (map[string]bool, error) {
+	f, err := os.Open(filepath.Join(dir, "vendor", "modules.txt"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	modules := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Module lines look like "# path version", "## explicit" lines are annotations.
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "#" {
+			modules[fields[1]] = true
+		}
+	}
+	return modules, scanner.Err()
+}
+
+// vendoredOf reports whether the module of a third-party package is in the vendor directory,
+// ok is false when there is nothing to report.
+func (res Result) vendoredOf(pkg string) (vendored, ok bool) {
+	p := res.packages[pkg]
+	if res.vendored == nil || p.Module == nil || p.Module.Main {
+		return false, false
+	}
+	return res.vendored[p.Module.Path], true
+}
+
+// pathVendored returns whether the module of every third-party package on the paths is
+// vendored.
+func (res Result) pathVendored() map[string]bool {
+	vendored := make(map[string]bool)
+	for _, p := range res.Paths {
+		for _, pkg := range p {
+			if v, ok := res.vendoredOf(pkg); ok {
+				vendored[pkg] = v
+			}
+		}
+	}
+	return vendored
+}
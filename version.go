@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// VersionReport explains the version of a module selected by minimal version selection.
+type VersionReport struct {
+	Module       string     `json:"module"`
+	Selected     string     `json:"selected"`
+	Requirements []Requirer `json:"requirements"`
+	Path         []string   `json:"path"`
+}
+
+// Requirer is a module version requiring the explained module.
+type Requirer struct {
+	Requirer string `json:"requirer"`
+	Version  string `json:"version"`
+	Selects  bool   `json:"selects"`
+}
+
+type versionCommand struct {
+	Args struct {
+		Module string `positional-arg-name:"module"`
+	} `positional-args:"yes" required:"yes"`
+
+	parser *flags.Parser
+	opts   *Opts
+}
+
+// selectedVersion returns the version of the module in the build list.
+func selectedVersion(module string) (string, error) {
+	cmd := goCommand("list", "-m", "-json", module)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go list failed: %v\n\n%s\n%s", err, cmd.String(), stderr.String())
+	}
+	var m Module
+	if err := json.Unmarshal(output, &m); err != nil {
+		return "", fmt.Errorf("go list failed: %v\n\n%s", err, cmd.String())
+	}
+	return m.Version, nil
+}
+
+// explainVersion lists every requirement of the module in the module graph. Minimal version
+// selection picks the highest of them, so the requirers of the selected version are the ones
+// forcing it. The path is a shortest requirement chain from a main module to the selected
+// version.
+func explainVersion(module, selected string, modGraph map[string][]string, mainModules []string) *VersionReport {
+	report := &VersionReport{Module: module, Selected: selected, Requirements: []Requirer{}, Path: []string{}}
+	for requirer, reqs := range modGraph {
+		for _, req := range reqs {
+			if path, version := splitVersion(req); path == module {
+				report.Requirements = append(report.Requirements, Requirer{Requirer: requirer, Version: version, Selects: version == selected})
+			}
+		}
+	}
+	sort.Slice(report.Requirements, func(i, j int) bool {
+		a, b := report.Requirements[i], report.Requirements[j]
+		if a.Selects != b.Selects {
+			return a.Selects
+		}
+		return a.Requirer < b.Requirer
+	})
+	if chain := requirementChain(mainModules, module+"@"+selected, modGraph); chain != nil {
+		report.Path = chain
+	}
+	return report
+}
+
+func (c *versionCommand) Execute(args []string) error {
+	module := c.Args.Module
+	selected, err := selectedVersion(module)
+	if err != nil {
+		return err
+	}
+	if selected == "" {
+		return fmt.Errorf("%s is the main module or has no version", module)
+	}
+	infof("Executing go mod graph command to get module requirements...")
+	modGraph, err := runGoModGraph()
+	if err != nil {
+		return err
+	}
+	mainModules, err := mainModulePaths()
+	if err != nil {
+		return err
+	}
+	report := explainVersion(module, selected, modGraph, mainModules)
+
+	return writeReport(c.parser, *c.opts, "version", Result{Target: module, Root: chainRoot(report.Path, mainModules), Version: report}, func(w io.Writer) error {
+		return printVersion(w, report)
+	})
+}
+
+func printVersion(w io.Writer, report *VersionReport) error {
+	fmt.Fprintf(w, "# %s\n", report.Module)
+	fmt.Fprintf(w, "selected version: %s\n", report.Selected)
+	fmt.Fprintf(w, "required by (%d):\n", len(report.Requirements))
+	for _, req := range report.Requirements {
+		if req.Selects {
+			fmt.Fprintf(w, "  %s requires %s, selected\n", req.Requirer, req.Version)
+		} else {
+			fmt.Fprintf(w, "  %s requires %s\n", req.Requirer, req.Version)
+		}
+	}
+	if len(report.Path) > 0 {
+		fmt.Fprintln(w, "requirement chain:")
+		for _, node := range report.Path {
+			fmt.Fprintf(w, "  %s\n", node)
+		}
+	}
+	return nil
+}
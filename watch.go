@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// watchInterval is how often --watch polls the files for changes.
+const watchInterval = time.Second
+
+// fileStamp is what tells a watched file changed.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// watchedFiles returns the stamps of the go.mod, go.sum, go.work and Go files below dir,
+// skipping hidden directories and testdata.
+func watchedFiles(dir string) (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if name != "go.mod" && name != "go.sum" && name != "go.work" && !strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+	return files, err
+}
+
+// changedFile returns a file added, removed or modified between two snapshots of the
+// watched files, or "" if none changed.
+func changedFile(before, after map[string]fileStamp) string {
+	for path, stamp := range after {
+		if before[path] != stamp {
+			return path
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			return path
+		}
+	}
+	return ""
+}
+
+// watch explains the targets, then explains them again and reprints the result every time
+// a watched file changes, until interrupted. Failures are reported without stopping, since
+// the code is expected to be broken at times while it is edited.
+func (c *pathCommand) watch(opts Opts, targetArgs []string) error {
+	dir := goDir
+	if dir == "" {
+		dir = "."
+	}
+	opts.Cache = false // the cache is keyed by go.mod and go.sum only
+	var last []byte
+	for n := 0; ; n++ {
+		files, err := watchedFiles(dir)
+		if err != nil {
+			return err
+		}
+		out, err := openOutput(opts)
+		if err != nil {
+			return err
+		}
+		output, failed, err := c.explainOnce(opts, newPrintOptions(c.parser, opts, out), targetArgs)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		} else {
+			if isTerminal(out) {
+				fmt.Fprint(out, "\033[H\033[2J")
+			}
+			out.Write(output)
+			switch {
+			case n == 0:
+			case bytes.Equal(output, last):
+				fmt.Fprintln(os.Stderr, "result unchanged")
+			default:
+				fmt.Fprintln(os.Stderr, "result changed")
+			}
+			if failed != nil {
+				fmt.Fprintln(os.Stderr, failed.Error())
+			}
+			last = output
+		}
+		if out != os.Stdout {
+			if err := out.Close(); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "watching %d files for changes...\n", len(files))
+		for {
+			time.Sleep(watchInterval)
+			now, err := watchedFiles(dir)
+			if err != nil {
+				return err
+			}
+			if path := changedFile(files, now); path != "" {
+				fmt.Fprintf(os.Stderr, "%s changed at %s, re-running\n", path, time.Now().Format("15:04:05"))
+				break
+			}
+		}
+	}
+}
+
+// explainOnce explains the targets and returns the printed result, with the error of
+// --fail-if-found or --fail-if-missing if the gate fails.
+func (c *pathCommand) explainOnce(opts Opts, popts printOptions, targetArgs []string) (output []byte, failed error, err error) {
+	var results []Result
+	if len(opts.Platforms) > 0 {
+		results, err = explainPlatforms(opts, targetArgs)
+	} else {
+		results, err = explain(opts, targetArgs)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	if err := printResults(&buf, popts, strings.Join(targetArgs, ", "), results); err != nil {
+		return nil, nil, err
+	}
+	return buf.Bytes(), opts.checkFound(results), nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// workspaceModules returns the modules of the go.work workspace, or nil outside of a
+// workspace.
+func workspaceModules() ([]Module, error) {
+	cmd := goCommand("env", "GOWORK")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go env failed: %v\n\n%s", err, cmd.String())
+	}
+	if gowork := strings.TrimSpace(string(output)); gowork == "" || gowork == "off" {
+		return nil, nil
+	}
+
+	return listModules()
+}
+
+// workspaceRoots returns the start packages of every workspace module: the package at the
+// module root, or if there is none, the packages of the module no loaded package imports.
+func workspaceRoots(modules []Module, packages []Package, pkgMap map[string]Package) []string {
+	imported := make(map[string]bool)
+	for _, p := range packages {
+		for _, imp := range p.Imports {
+			imported[imp] = true
+		}
+	}
+	var roots []string
+	for _, m := range modules {
+		if _, ok := pkgMap[m.Path]; ok {
+			roots = append(roots, m.Path)
+			continue
+		}
+		for _, p := range packages {
+			if p.Module != nil && p.Module.Path == m.Path && !imported[p.ImportPath] {
+				roots = append(roots, p.ImportPath)
+			}
+		}
+	}
+	return roots
+}